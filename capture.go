@@ -6,26 +6,65 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"sync"
 	"time"
 
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/hooks"
 	"github.com/speakeasy-api/speakeasy-go-sdk/internal/log"
 	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
 	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
 	"go.uber.org/zap"
 )
 
-var maxCaptureSize = 1 * 1024 * 1024
-
-var timeNow = func() time.Time {
-	return time.Now()
+func timeNow() time.Time {
+	return hooks.TimeNow()
 }
 
-var timeSince = func(t time.Time) time.Duration {
-	return time.Since(t)
+func timeSince(t time.Time) time.Duration {
+	return hooks.TimeSince(t)
 }
 
 type handlerFunc func(http.ResponseWriter, *http.Request) error
 
+// Capture captures a request to the default SDK instance for frameworks that don't use http.Handlers, see Speakeasy.Capture.
+func Capture(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request) (*http.Request, error), resolver PathHintResolver) error {
+	return defaultInstance.Capture(w, r, next, resolver)
+}
+
+// Capture captures a request to the current instance of the SDK for frameworks that don't use http.Handlers, it is used by the
+// speakeasygin, speakeasyecho and speakeasyfiber modules and can be used to integrate other frameworks. next is called with the
+// http.ResponseWriter and http.Request the framework should use for the rest of its handler chain, and returns the request as
+// last seen by the framework (used by the CustomerIDResolver) or nil if it is unchanged, and any error returned by the handler
+// chain, which Capture returns. Errors handled by the framework can be recorded with the RecordError method of the
+// MiddlewareController. The path hint of the request is resolved by resolver (which may be nil) if it isn't matched by the
+// OpenAPI document, resolved by the PathHintResolvers of the config or provided by the MiddlewareController.
+func (s *Speakeasy) Capture(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request) (*http.Request, error), resolver PathHintResolver) error {
+	return s.handleRequestResponseError(w, r, func(w http.ResponseWriter, r *http.Request) error {
+		req, err := next(w, r)
+
+		if ctrl, ok := MiddlewareController(r); ok && req != nil {
+			ctrl.setRequest(req)
+		}
+
+		return err
+	}, func(r *http.Request) string {
+		if pathHint := s.MatchOpenAPIPath(r); pathHint != "" {
+			return pathHint
+		}
+
+		if pathHint := resolvePathHint(r, s.config.PathHintResolvers); pathHint != "" {
+			return pathHint
+		}
+
+		if resolver == nil {
+			return ""
+		}
+
+		return resolver.ResolvePathHint(r)
+	})
+}
+
 func (s *Speakeasy) handleRequestResponse(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, capturePathHint func(r *http.Request) string) {
 	err := s.handleRequestResponseError(w, r, func(w http.ResponseWriter, r *http.Request) error {
 		next.ServeHTTP(w, r)
@@ -40,7 +79,28 @@ func (s *Speakeasy) handleRequestResponseError(w http.ResponseWriter, r *http.Re
 	//nolint:ifshort
 	startTime := timeNow()
 
-	cw := NewCaptureWriter(w, maxCaptureSize)
+	// The controller is always provided so handlers can use it regardless of whether the request is captured
+	ctx, c := contextWithController(r.Context(), s)
+	r = r.WithContext(ctx)
+
+	if s.config.RequestID != nil {
+		c.requestID = s.resolveRequestID(r)
+	}
+
+	if !s.shouldCapture(r) {
+		return next(w, r)
+	}
+
+	// Requests that weren't sampled are still buffered if capture rules could force them to be captured
+	sampled := s.isSampled(r, capturePathHint)
+	if !sampled && !s.hasCaptureRules() {
+		return next(w, r)
+	}
+
+	// Integrations can tell whether the request is recorded from the controller, as it isn't if filtered or not sampled
+	c.recording = true
+
+	cw := NewCaptureWriter(w, hooks.MaxCaptureSize)
 
 	if r.Body != nil {
 		// We need to duplicate the request body, because it should be consumed by the next handler first before we can read it
@@ -50,19 +110,90 @@ func (s *Speakeasy) handleRequestResponseError(w http.ResponseWriter, r *http.Re
 		r.Body = io.NopCloser(tee)
 	}
 
-	ctx, c := contextWithController(r.Context(), s)
-	r = r.WithContext(ctx)
+	var once sync.Once
+	capture := func() {
+		once.Do(func() {
+			// The handler of a stream captured early may still be using the controller
+			c := c.snapshot()
 
-	err := next(cw.GetResponseWriter(), r)
+			if c.skipCapture {
+				return
+			}
+
+			if !sampled && !s.matchesCaptureRules(cw, startTime, c) {
+				return
+			}
+
+			// if developer has provided a customer ID use it, otherwise resolve it from the latest request seen by the framework
+			if c.customerID == "" && s.config.CustomerIDResolver != nil {
+				req := r
+				if c.request != nil {
+					req = c.request
+				}
+
+				c.customerID = s.config.CustomerIDResolver(req)
+			}
+
+			pathHint := capturePathHint(r)
+			pathHint = pathhints.NormalizePathHint(pathHint)
+
+			// GraphQL requests are all served on the same path so they are distinguished by their operation
+			if c.graphql = s.resolveGraphQLOperation(r, cw); c.graphql != nil {
+				pathHint = getGraphQLPathHint(pathHint, r, c.graphql)
+			}
 
-	pathHint := capturePathHint(r)
-	pathHint = pathhints.NormalizePathHint(pathHint)
+			// JSON-RPC requests are also served on the same path so they are distinguished by the method called
+			if c.jsonrpc = s.resolveJSONRPCCall(r, cw); c.jsonrpc != nil {
+				pathHint = getJSONRPCPathHint(pathHint, r, c.jsonrpc)
+			}
 
-	// if developer has provided a path hint use it, otherwise use the pathHint from the request
-	if c.pathHint != "" {
-		pathHint = c.pathHint
+			// if developer has provided a path hint use it, otherwise use the pathHint from the request
+			if c.pathHint != "" {
+				pathHint = c.pathHint
+			}
+
+			s.sendCapture(cw, r, startTime, pathHint, c)
+		})
+	}
+
+	// Long-lived streams may never return from the handler so we allow them to be captured early
+	stream := streamConfig{
+		startTime:   startTime,
+		maxEvents:   s.config.ServerSentEvents.MaxEvents,
+		maxDuration: s.config.ServerSentEvents.MaxDuration,
+		onLimit:     capture,
 	}
+	if isWebSocketUpgrade(r) {
+		stream.webSocket = &s.config.WebSocket
+	}
+	cw.setStreamConfig(stream)
+
+	// Capture requests whose handler panics, then re-panic so any recovery middleware still runs
+	defer func() {
+		if p := recover(); p != nil {
+			cw.recordPanic(p, debug.Stack())
+
+			if !cw.IsWebSocket() {
+				cw.finish()
+				capture()
+			}
 
+			panic(p)
+		}
+	}()
+
+	err := next(cw.GetResponseWriter(), r)
+
+	// WebSocket connections are captured once the connection is closed, which may be after the handler returns
+	if !cw.IsWebSocket() {
+		cw.finish()
+		capture()
+	}
+
+	return err
+}
+
+func (s *Speakeasy) sendCapture(cw *captureWriter, r *http.Request, startTime time.Time, pathHint string, c *controller) {
 	// Used for load testing: set this to true and the capture GRPC call is invoked inline.
 	// This will cause the endpoint latency to be added to the GRPC request/response latency
 	if os.Getenv("SPEAKEASY_SDK_CAPTURE_INLINE") == "true" {
@@ -70,14 +201,14 @@ func (s *Speakeasy) handleRequestResponseError(w http.ResponseWriter, r *http.Re
 	} else {
 		go s.captureRequestResponse(cw, r, startTime, pathHint, c)
 	}
-	return err
 }
 
 //nolint:nolintlint,contextcheck
 func (s *Speakeasy) captureRequestResponse(cw *captureWriter, r *http.Request, startTime time.Time, pathHint string, c *controller) {
 	var ctx context.Context = valueOnlyContext{r.Context()}
 
-	if cw.IsReqValid() && cw.GetReqBuffer().Len() == 0 && r.Body != nil {
+	// The handler may still be reading the body of an incomplete capture
+	if cw.IsReqValid() && cw.GetReqBuffer().Len() == 0 && r.Body != nil && !cw.IsIncomplete() {
 		// Read the body just in case it was not read in the handler
 		//nolint: errcheck
 		io.Copy(io.Discard, r.Body)
@@ -89,11 +220,17 @@ func (s *Speakeasy) captureRequestResponse(cw *captureWriter, r *http.Request, s
 		return
 	}
 
+	// Outbound requests may be associated with the Api of the upstream they are made to
+	apiID, versionID := s.config.ApiID, s.config.VersionID
+	if c.apiID != "" {
+		apiID, versionID = c.apiID, c.versionID
+	}
+
 	s.grpcClient.SendToIngest(ctx, &ingest.IngestRequest{
 		Har:        string(harData),
 		PathHint:   pathHint,
-		ApiId:      s.config.ApiID,
-		VersionId:  s.config.VersionID,
+		ApiId:      apiID,
+		VersionId:  versionID,
 		CustomerId: c.customerID,
 		//nolint:nosnakecase
 		MaskingMetadata: &ingest.IngestRequest_MaskingMetadata{
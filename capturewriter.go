@@ -1,12 +1,21 @@
 package speakeasy
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
 type captureWriter struct {
+	// mu guards the recorded data, as streams can exceed their limits (and be captured) while the handler is still writing
+	mu            sync.Mutex
 	reqW          *requestWriter
 	origResW      http.ResponseWriter
 	resW          *responseWriter
@@ -16,8 +25,46 @@ type captureWriter struct {
 	resValid      bool
 	status        int
 	statusWritten bool
+	hijacked      bool
+	requestSize   int
 	responseSize  int
 	maxBuffer     int
+	resHeader     http.Header
+	resTrailers   http.Header
+	stream        streamConfig
+	sse           *sseRecorder
+	ws            *wsRecorder
+	detached      bool
+	finished      bool
+	incomplete    bool
+	streamTimer   *time.Timer
+	timings       captureTimings
+	panicked      *capturedPanic
+}
+
+// capturedPanic records a panic that occurred in the handler.
+type capturedPanic struct {
+	Value string `json:"value"`
+	Stack string `json:"stack"`
+}
+
+// captureTimings records when the phases of the request/response occurred.
+type captureTimings struct {
+	lastRequestRead time.Time
+	firstByte       time.Time
+	lastByte        time.Time
+	end             time.Time
+}
+
+// streamConfig controls the capture of long-lived streamed responses.
+type streamConfig struct {
+	startTime   time.Time
+	maxEvents   int
+	maxDuration time.Duration
+	// onLimit is called once a stream exceeds its limits, allowing the capture to be sent before the handler returns.
+	onLimit func()
+	// webSocket is set if the request is a WebSocket upgrade, causing the hijacked connection to be recorded.
+	webSocket *WebSocketConfig
 }
 
 func NewCaptureWriter(origResW http.ResponseWriter, maxBuffer int) *captureWriter {
@@ -45,8 +92,128 @@ func (c *captureWriter) GetRequestWriter() *requestWriter {
 	return c.reqW
 }
 
-func (c *captureWriter) GetResponseWriter() *responseWriter {
-	return c.resW
+// GetResponseWriter returns the http.ResponseWriter to be passed to the handler, it only implements the optional
+// http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom interfaces if the original http.ResponseWriter does,
+// so handlers checking for them can still choose an appropriate fallback.
+//
+//nolint:cyclop
+func (c *captureWriter) GetResponseWriter() http.ResponseWriter {
+	features := 0
+
+	if _, ok := c.origResW.(http.Flusher); ok {
+		features |= featureFlusher
+	}
+
+	if _, ok := c.origResW.(http.Hijacker); ok {
+		features |= featureHijacker
+	}
+
+	if _, ok := c.origResW.(http.Pusher); ok {
+		features |= featurePusher
+	}
+
+	if _, ok := c.origResW.(io.ReaderFrom); ok {
+		features |= featureReaderFrom
+	}
+
+	rw, f, h, p, rf := c.resW, flusher{c}, hijacker{c}, pusher{c}, readerFrom{c}
+
+	switch features {
+	case featureFlusher:
+		return struct {
+			*responseWriter
+			flusher
+		}{rw, f}
+	case featureHijacker:
+		return struct {
+			*responseWriter
+			hijacker
+		}{rw, h}
+	case featureFlusher | featureHijacker:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, f, h}
+	case featurePusher:
+		return struct {
+			*responseWriter
+			pusher
+		}{rw, p}
+	case featureFlusher | featurePusher:
+		return struct {
+			*responseWriter
+			flusher
+			pusher
+		}{rw, f, p}
+	case featureHijacker | featurePusher:
+		return struct {
+			*responseWriter
+			hijacker
+			pusher
+		}{rw, h, p}
+	case featureFlusher | featureHijacker | featurePusher:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+		}{rw, f, h, p}
+	case featureReaderFrom:
+		return struct {
+			*responseWriter
+			readerFrom
+		}{rw, rf}
+	case featureFlusher | featureReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, f, rf}
+	case featureHijacker | featureReaderFrom:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, h, rf}
+	case featureFlusher | featureHijacker | featureReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, f, h, rf}
+	case featurePusher | featureReaderFrom:
+		return struct {
+			*responseWriter
+			pusher
+			readerFrom
+		}{rw, p, rf}
+	case featureFlusher | featurePusher | featureReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			pusher
+			readerFrom
+		}{rw, f, p, rf}
+	case featureHijacker | featurePusher | featureReaderFrom:
+		return struct {
+			*responseWriter
+			hijacker
+			pusher
+			readerFrom
+		}{rw, h, p, rf}
+	case featureFlusher | featureHijacker | featurePusher | featureReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+			readerFrom
+		}{rw, f, h, p, rf}
+	default:
+		return rw
+	}
 }
 
 func (c *captureWriter) IsReqValid() bool {
@@ -69,11 +236,60 @@ func (c *captureWriter) GetStatus() int {
 	return c.status
 }
 
+// GetRequestSize returns the number of bytes read from the request body.
+func (c *captureWriter) GetRequestSize() int {
+	return c.requestSize
+}
+
+// GetResponseSize returns the number of bytes written to the response body.
 func (c *captureWriter) GetResponseSize() int {
 	return c.responseSize
 }
 
+// GetResponseTrailers returns any trailers set by the handler, either declared in the Trailer header
+// or set using the http.TrailerPrefix. Trailers are only available once the handler has finished.
+func (c *captureWriter) GetResponseTrailers() http.Header {
+	if c.resTrailers == nil {
+		return http.Header{}
+	}
+
+	return c.resTrailers
+}
+
+func (c *captureWriter) collectTrailers() http.Header {
+	trailers := http.Header{}
+
+	header := c.origResW.Header()
+
+	for _, declared := range c.GetResponseHeader().Values("Trailer") {
+		for _, key := range strings.Split(declared, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if values, ok := header[key]; ok {
+				trailers[key] = values
+			}
+		}
+	}
+
+	for key, values := range header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			trailers[http.CanonicalHeaderKey(strings.TrimPrefix(key, http.TrailerPrefix))] = values
+		}
+	}
+
+	return trailers
+}
+
 func (c *captureWriter) writeReq(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.detached {
+		return len(p), nil
+	}
+
+	c.requestSize += len(p)
+	c.timings.lastRequestRead = timeNow()
+
 	// Check if we have exceeded the buffer size and if so drop rest of request
 	if (c.reqBuf.Len() + c.resBuf.Len() + len(p)) > c.maxBuffer {
 		c.reqValid = false
@@ -87,11 +303,185 @@ func (c *captureWriter) writeReq(p []byte) (int, error) {
 	return len(p), nil
 }
 
+func (c *captureWriter) IsHijacked() bool {
+	return c.hijacked
+}
+
+// IsIncomplete returns true if the capture was sent before the response completed.
+func (c *captureWriter) IsIncomplete() bool {
+	return c.incomplete
+}
+
+// GetResponseHeader returns the response headers as they were when the status was written, or the current headers
+// if the status hasn't been written yet.
+func (c *captureWriter) GetResponseHeader() http.Header {
+	if c.resHeader != nil {
+		return c.resHeader
+	}
+
+	return c.origResW.Header()
+}
+
+func (c *captureWriter) GetServerSentEvents() []*serverSentEvent {
+	if c.sse == nil {
+		return nil
+	}
+
+	return c.sse.events
+}
+
+func (c *captureWriter) GetWebSocketMessages() []*webSocketMessage {
+	if c.ws == nil || !c.ws.captureMessages {
+		return nil
+	}
+
+	return c.ws.GetMessages()
+}
+
+// IsWebSocket returns true if the connection was hijacked to be upgraded to a WebSocket connection.
+func (c *captureWriter) IsWebSocket() bool {
+	return c.ws != nil
+}
+
+// GetPanic returns the panic that occurred in the handler, or nil if the handler didn't panic.
+func (c *captureWriter) GetPanic() *capturedPanic {
+	return c.panicked
+}
+
+func (c *captureWriter) GetTimings() captureTimings {
+	return c.timings
+}
+
+// finish records the time the handler finished, or the time the capture was sent for an incomplete capture.
+func (c *captureWriter) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.finishLocked()
+}
+
+// markIncomplete marks the capture as incomplete if it hasn't already been sent.
+func (c *captureWriter) markIncomplete() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.detached {
+		c.incomplete = true
+	}
+}
+
+// finishLocked must be called with the lock held.
+func (c *captureWriter) finishLocked() {
+	if c.detached {
+		return
+	}
+
+	c.finished = true
+	c.timings.end = timeNow()
+
+	if c.streamTimer != nil {
+		c.streamTimer.Stop()
+	}
+
+	// The headers of the underlying ResponseWriter can't be accessed once the handler has returned (and the capture may be
+	// built after it has), so they are copied now. The headers of a hijacked connection are recorded from the handshake.
+	if c.hijacked {
+		if c.resHeader == nil {
+			c.resHeader = http.Header{}
+		}
+
+		return
+	}
+
+	if c.resHeader == nil {
+		c.resHeader = c.origResW.Header().Clone()
+	}
+
+	// The handler may still be writing to the header of an incomplete capture
+	if !c.incomplete {
+		c.resTrailers = c.collectTrailers()
+	}
+}
+
+// recordPanic records a panic that occurred in the handler, if the handler hadn't written a status it is recorded
+// as a 500 as that is what the user's recovery middleware or the http.Server will most likely respond with.
+func (c *captureWriter) recordPanic(value interface{}, stack []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.detached {
+		return
+	}
+
+	c.panicked = &capturedPanic{
+		Value: fmt.Sprint(value),
+		Stack: string(stack),
+	}
+
+	if !c.statusWritten {
+		c.status = http.StatusInternalServerError
+	}
+}
+
+func (c *captureWriter) setStreamConfig(cfg streamConfig) {
+	c.stream = cfg
+}
+
+// detach stops the captureWriter recording any further data, allowing the capture to be built while the handler continues to write.
+func (c *captureWriter) detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.detached = true
+}
+
 func (c *captureWriter) writeRes(p []byte) (int, error) {
 	if c.resBuf.Len() == 0 {
 		c.writeHeader(c.status)
 	}
 
+	c.recordRes(p)
+
+	n, err := c.origResW.Write(p)
+
+	c.recordWritten(p[:n], err)
+
+	return n, err
+}
+
+// recordWritten records data written to the underlying http.ResponseWriter, sending the capture early if it exceeds the stream limits.
+func (c *captureWriter) recordWritten(p []byte, err error) {
+	c.mu.Lock()
+
+	if c.detached {
+		c.mu.Unlock()
+		return
+	}
+
+	if err != nil {
+		c.resValid = false
+	}
+
+	c.responseSize += len(p)
+	c.timings.lastByte = timeNow()
+
+	limited := c.recordStream(p)
+
+	c.mu.Unlock()
+
+	if limited {
+		c.stream.onLimit()
+	}
+}
+
+func (c *captureWriter) recordRes(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.detached {
+		return
+	}
+
 	// Check if we have exceeded the buffer size and if so drop rest of response
 	if (c.reqBuf.Len() + c.resBuf.Len() + len(p)) > c.maxBuffer {
 		c.resValid = false
@@ -101,22 +491,139 @@ func (c *captureWriter) writeRes(p []byte) (int, error) {
 			c.resValid = false
 		}
 	}
+}
 
-	n, err := c.origResW.Write(p)
+func (c *captureWriter) readFrom(src io.Reader) (int64, error) {
+	c.writeHeader(c.status)
+
+	rf, ok := c.origResW.(io.ReaderFrom)
+	if !ok {
+		// hide our own ReadFrom from io.Copy so it falls back to calling Write
+		return io.Copy(writerOnly{c.resW}, src)
+	}
+
+	n, err := rf.ReadFrom(io.TeeReader(src, writerFunc(func(p []byte) (int, error) {
+		c.recordRes(p)
+		c.recordWritten(p, nil)
+		return len(p), nil
+	})))
 	if err != nil {
+		c.mu.Lock()
 		c.resValid = false
+		c.mu.Unlock()
 	}
 
-	c.responseSize += n
-
 	return n, err
 }
 
+func (c *captureWriter) flush() {
+	c.writeHeader(c.status)
+
+	if f, ok := c.origResW.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *captureWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := c.origResW.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.hijacked = true
+
+	if c.stream.webSocket != nil && c.stream.onLimit != nil {
+		c.ws = newWSRecorder(c, *c.stream.webSocket, c.stream.onLimit)
+		conn, rw = c.ws.wrap(conn, rw)
+	}
+
+	return conn, rw, nil
+}
+
+func (c *captureWriter) push(target string, opts *http.PushOptions) error {
+	p, ok := c.origResW.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+
+	return p.Push(target, opts)
+}
+
+// recordStream must be called with the lock held, it returns true if the stream exceeded its limits and onLimit should be called
+// once the lock is released.
+func (c *captureWriter) recordStream(p []byte) bool {
+	if c.sse == nil || c.detached {
+		return false
+	}
+
+	c.sse.write(p)
+
+	limitReached := c.stream.maxEvents > 0 && len(c.sse.events) >= c.stream.maxEvents
+	durationExceeded := c.stream.maxDuration > 0 && timeSince(c.stream.startTime) >= c.stream.maxDuration
+
+	if !(limitReached || durationExceeded) || c.stream.onLimit == nil {
+		return false
+	}
+
+	c.limitStream()
+
+	return true
+}
+
+// limitStream must be called with the lock held, it marks the capture as incomplete and stops recording the stream.
+func (c *captureWriter) limitStream() {
+	c.incomplete = true
+	c.finishLocked()
+	c.detached = true
+}
+
+// startStreamTimer sends the capture of a stream once it exceeds its maximum duration, even if nothing is written to an idle stream.
+func (c *captureWriter) startStreamTimer() {
+	if c.stream.maxDuration <= 0 || c.stream.onLimit == nil {
+		return
+	}
+
+	c.streamTimer = time.AfterFunc(c.stream.maxDuration-timeSince(c.stream.startTime), func() {
+		c.mu.Lock()
+
+		if c.finished || c.detached {
+			c.mu.Unlock()
+			return
+		}
+
+		c.limitStream()
+
+		c.mu.Unlock()
+
+		c.stream.onLimit()
+	})
+}
+
 func (c *captureWriter) writeHeader(statusCode int) {
 	if !c.statusWritten {
 		c.status = statusCode
 		c.statusWritten = true
+		c.resHeader = c.origResW.Header().Clone()
+		c.timings.firstByte = timeNow()
+
+		isEventStream := false
+		if mediaType, _, err := mime.ParseMediaType(c.resHeader.Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+			c.sse = newSSERecorder(c.stream.startTime)
+			isEventStream = true
+		}
+
 		c.origResW.WriteHeader(statusCode)
+
+		if isEventStream {
+			c.mu.Lock()
+			c.startStreamTimer()
+			c.mu.Unlock()
+		}
 	}
 }
 
@@ -147,3 +654,67 @@ func (r *responseWriter) WriteHeader(statusCode int) {
 func (r *responseWriter) Header() http.Header {
 	return r.cw.origResW.Header()
 }
+
+// Unwrap returns the original http.ResponseWriter, this is used by http.ResponseController to access the methods of the original writer.
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.cw.origResW
+}
+
+type writerOnly struct {
+	io.Writer
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// The optional interfaces implemented by the original http.ResponseWriter.
+const (
+	featureFlusher = 1 << iota
+	featureHijacker
+	featurePusher
+	featureReaderFrom
+)
+
+type flusher struct {
+	cw *captureWriter
+}
+
+var _ http.Flusher = flusher{}
+
+func (f flusher) Flush() {
+	f.cw.flush()
+}
+
+type hijacker struct {
+	cw *captureWriter
+}
+
+var _ http.Hijacker = hijacker{}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.cw.hijack()
+}
+
+type pusher struct {
+	cw *captureWriter
+}
+
+var _ http.Pusher = pusher{}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.cw.push(target, opts)
+}
+
+type readerFrom struct {
+	cw *captureWriter
+}
+
+var _ io.ReaderFrom = readerFrom{}
+
+// ReadFrom allows the underlying http.ResponseWriter's fast path to be used.
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	return r.cw.readFrom(src)
+}
@@ -0,0 +1,189 @@
+package speakeasy_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/har"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeakeasy_Middleware_ResponseWriter_Interfaces_Success(t *testing.T) {
+	tests := []struct {
+		name         string
+		writer       func(w *httptest.ResponseRecorder) http.ResponseWriter
+		handler      func(t *testing.T, w http.ResponseWriter)
+		wantBody     string
+		wantFlushed  bool
+		wantResponse string
+	}{
+		{
+			name: "flushes streamed response",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+
+				f, ok := w.(http.Flusher)
+				require.True(t, ok)
+
+				_, err := w.Write([]byte("hello "))
+				assert.NoError(t, err)
+				f.Flush()
+				_, err = w.Write([]byte("world"))
+				assert.NoError(t, err)
+			},
+			wantBody:    "hello world",
+			wantFlushed: true,
+		},
+		{
+			name: "copies response using ReadFrom",
+			writer: func(w *httptest.ResponseRecorder) http.ResponseWriter {
+				return &readerFromRecorder{w}
+			},
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+
+				rf, ok := w.(io.ReaderFrom)
+				require.True(t, ok)
+
+				_, err := rf.ReadFrom(strings.NewReader("copied body"))
+				assert.NoError(t, err)
+			},
+			wantBody: "copied body",
+		},
+		{
+			name: "unwraps to original response writer",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+
+				u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+				require.True(t, ok)
+
+				_, ok = u.Unwrap().(*httptest.ResponseRecorder)
+				assert.True(t, ok)
+			},
+		},
+		{
+			name: "doesn't implement interfaces unsupported by underlying writer",
+			handler: func(t *testing.T, w http.ResponseWriter) {
+				t.Helper()
+
+				_, ok := w.(http.Pusher)
+				assert.False(t, ok)
+
+				_, ok = w.(http.Hijacker)
+				assert.False(t, ok)
+
+				_, ok = w.(io.ReaderFrom)
+				assert.False(t, ok)
+
+				_, ok = w.(http.Flusher)
+				assert.True(t, ok)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					var h har.HAR
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					assert.Equal(t, tt.wantBody, h.Log.Entries[0].Response.Content.Text)
+					wg.Done()
+				}),
+			})
+
+			w := httptest.NewRecorder()
+
+			var rw http.ResponseWriter = w
+			if tt.writer != nil {
+				rw = tt.writer(w)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+			require.NoError(t, err)
+
+			sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				tt.handler(t, w)
+			})).ServeHTTP(rw, req)
+
+			wg.Wait()
+
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantFlushed, w.Flushed)
+		})
+	}
+}
+
+// readerFromRecorder is a httptest.ResponseRecorder implementing io.ReaderFrom, as the writers of http.Server do.
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+func TestSpeakeasy_Middleware_ResponseWriter_Hijack_Success(t *testing.T) {
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {}),
+	})
+
+	s := httptest.NewServer(sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h, ok := w.(http.Hijacker)
+		require.True(t, ok)
+
+		conn, rw, err := h.Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		assert.NoError(t, err)
+		assert.NoError(t, rw.Flush())
+	})))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /test HTTP/1.1\r\nHost: test.com\r\n\r\n"))
+	require.NoError(t, err)
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "hijacked", string(body))
+}
@@ -0,0 +1,86 @@
+package speakeasy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// tlsInfo records the TLS connection state of a request.
+type tlsInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ALPN        string `json:"alpn,omitempty"`
+	ServerName  string `json:"serverName,omitempty"`
+}
+
+// outboundConnection records the connection an outbound request was made on.
+type outboundConnection struct {
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	tls        *tls.ConnectionState
+}
+
+// getServerIPAddress returns the IP address of the server that accepted the connection for the request, the local address
+// for requests handled by the middleware or the remote address for outbound requests.
+func (h *harBuilder) getServerIPAddress(r *http.Request, c *controller) string {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if c.outbound != nil {
+		addr, ok = c.outbound.remoteAddr, c.outbound.remoteAddr != nil
+	}
+
+	if !ok || addr == nil {
+		return ""
+	}
+
+	return hostWithoutPort(addr.String())
+}
+
+// getConnection returns the port of the client connection the request was made on, which identifies the connection
+// as required by the HAR spec.
+func (h *harBuilder) getConnection(r *http.Request, c *controller) string {
+	addr := r.RemoteAddr
+	if c.outbound != nil {
+		if c.outbound.localAddr == nil {
+			return ""
+		}
+
+		addr = c.outbound.localAddr.String()
+	}
+
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		return port
+	}
+
+	return ""
+}
+
+func getTLSInfo(state *tls.ConnectionState) *tlsInfo {
+	if state == nil {
+		return nil
+	}
+
+	return &tlsInfo{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		ServerName:  state.ServerName,
+	}
+}
+
+// tlsVersionName returns the name of a TLS version, as tls.VersionName isn't available in all supported versions of Go.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
@@ -2,7 +2,9 @@ package speakeasy
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync"
 )
 
 const (
@@ -201,6 +203,28 @@ func WithResponseFieldMaskNumber(fields []string, masks ...string) MaskingOption
 	}
 }
 
+// WithGraphQLVariableMask will mask the specified GraphQL variables with an optional mask string, replacing the whole value
+// of each variable in the captured request body. Requires GraphQL capture to be enabled with Config.GraphQL.
+// If no mask is provided, the value will be masked with the default mask.
+// If a single mask is provided, it will be used for all variables.
+// If the number of masks provided is equal to the number of variables, masks will be used in order.
+// Otherwise, the masks will be used in order until it they are exhausted. If the masks are exhausted, the default mask will be used.
+// (defaults to "__masked__").
+func WithGraphQLVariableMask(variables []string, masks ...string) MaskingOption {
+	return func(c *controller) {
+		for i, variable := range variables {
+			switch {
+			case len(masks) == 1:
+				c.graphqlVariableMasks[variable] = masks[0]
+			case len(masks) > i:
+				c.graphqlVariableMasks[variable] = masks[i]
+			default:
+				c.graphqlVariableMasks[variable] = DefaultStringMask
+			}
+		}
+	}
+}
+
 type contextKey int
 
 const (
@@ -208,8 +232,25 @@ const (
 )
 
 type controller struct {
+	// mu guards the fields set by the handler, as streams can be captured while their handler is still running
+	mu                       sync.Mutex
 	pathHint                 string
 	customerID               string
+	requestID                string
+	statusText               string
+	apiID                    string
+	versionID                string
+	metadata                 map[string]string
+	request                  *http.Request
+	outbound                 *outboundConnection
+	upstream                 *upstreamRequest
+	graphql                  *graphqlOperation
+	jsonrpc                  *jsonrpcCall
+	forceCapture             bool
+	skipCapture              bool
+	recording                bool
+	skipBodies               bool
+	handlerErrors            []*handlerError
 	queryStringMasks         map[string]string
 	requestHeaderMasks       map[string]string
 	requestCookieMasks       map[string]string
@@ -219,27 +260,99 @@ type controller struct {
 	responseCookieMasks      map[string]string
 	responseFieldMasksString map[string]string
 	responseFieldMasksNumber map[string]string
+	graphqlVariableMasks     map[string]string
 	sdkInstance              *Speakeasy
 }
 
 // MiddlewareController will return the speakeasy middleware controller from the current request,
 // if the current request is monitored by the speakeasy middleware.
 func MiddlewareController(r *http.Request) (*controller, bool) {
-	c, _ := r.Context().Value(controllerKey).(*controller)
+	return ControllerFromContext(r.Context())
+}
+
+// ControllerFromContext will return the speakeasy controller from the context of the current request or gRPC call,
+// if it is monitored by the speakeasy middleware or interceptors.
+func ControllerFromContext(ctx context.Context) (*controller, bool) {
+	c, _ := ctx.Value(controllerKey).(*controller)
 	return c, c != nil
 }
 
 // PathHint will allow you to provide a path hint for the current request.
 func (c *controller) PathHint(pathHint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.pathHint = pathHint
 }
 
 // CustomerID will allow you to associate a customer ID with the current request.
 func (c *controller) CustomerID(customerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.customerID = customerID
 }
 
+// Tag will add a key/value pair to the metadata of the current request, for example the tenant or plan tier of the customer.
+func (c *controller) Tag(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metadata[key] = value
+}
+
+// Metadata will add the key/value pairs to the metadata of the current request.
+func (c *controller) Metadata(metadata map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range metadata {
+		c.metadata[key] = value
+	}
+}
+
+// Recording will return whether the current request is being recorded to be captured, which it isn't if it was filtered or
+// not sampled. Framework integrations can use it to skip the work of recording requests that won't be captured.
+func (c *controller) Recording() bool {
+	return c.recording
+}
+
+// RequestID will return the ID recorded on the capture of the current request, allowing the capture to be found from logs.
+// The ID is only available if a RequestID config has been provided.
+func (c *controller) RequestID() string {
+	return c.requestID
+}
+
+// ForceCapture will mark the current request to be captured even if it wasn't sampled,
+// this requires a capture rule matching forced requests to be configured.
+func (c *controller) ForceCapture() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.forceCapture = true
+}
+
+// SkipCapture will prevent the current request from being captured, the capture is discarded once the handler returns.
+func (c *controller) SkipCapture() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.skipCapture = true
+}
+
+// SkipBodies will prevent the request and response bodies of the current request from being captured,
+// only the metadata of the request (such as its headers, status and timings) will be captured.
+func (c *controller) SkipBodies() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.skipBodies = true
+}
+
 func (c *controller) Masking(opts ...MaskingOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for _, opt := range opts {
 		opt(c)
 	}
@@ -249,6 +362,87 @@ func (c *controller) GetSDKInstance() *Speakeasy {
 	return c.sdkInstance
 }
 
+// handlerError records an error returned by a handler to the framework, such as an error returned to Echo or collected by Gin.
+type handlerError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// RecordError will record an error returned by a handler to the framework in the _errors field of the capture of the current
+// request, allowing errors handled by the framework rather than the handler to be captured.
+func (c *controller) RecordError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlerErrors = append(c.handlerErrors, &handlerError{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	})
+}
+
+// setRequest records the latest request seen by the framework, which customer IDs are resolved from.
+func (c *controller) setRequest(r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.request = r
+}
+
+// setUpstream records the upstream of a reverse proxy that served the request.
+func (c *controller) setUpstream(upstream *upstreamRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.upstream = upstream
+}
+
+// snapshot returns a copy of the controller, allowing the capture of a stream to be built while its handler may still be
+// using the controller.
+func (c *controller) snapshot() *controller {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &controller{
+		pathHint:                 c.pathHint,
+		customerID:               c.customerID,
+		requestID:                c.requestID,
+		statusText:               c.statusText,
+		apiID:                    c.apiID,
+		versionID:                c.versionID,
+		metadata:                 copyStringMap(c.metadata),
+		request:                  c.request,
+		outbound:                 c.outbound,
+		upstream:                 c.upstream,
+		graphql:                  c.graphql,
+		jsonrpc:                  c.jsonrpc,
+		forceCapture:             c.forceCapture,
+		skipCapture:              c.skipCapture,
+		recording:                c.recording,
+		skipBodies:               c.skipBodies,
+		handlerErrors:            append([]*handlerError{}, c.handlerErrors...),
+		queryStringMasks:         copyStringMap(c.queryStringMasks),
+		requestHeaderMasks:       copyStringMap(c.requestHeaderMasks),
+		requestCookieMasks:       copyStringMap(c.requestCookieMasks),
+		requestFieldMasksString:  copyStringMap(c.requestFieldMasksString),
+		requestFieldMasksNumber:  copyStringMap(c.requestFieldMasksNumber),
+		responseHeaderMasks:      copyStringMap(c.responseHeaderMasks),
+		responseCookieMasks:      copyStringMap(c.responseCookieMasks),
+		responseFieldMasksString: copyStringMap(c.responseFieldMasksString),
+		responseFieldMasksNumber: copyStringMap(c.responseFieldMasksNumber),
+		graphqlVariableMasks:     copyStringMap(c.graphqlVariableMasks),
+		sdkInstance:              c.sdkInstance,
+	}
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+
+	return copied
+}
+
 func contextWithController(ctx context.Context, sdk *Speakeasy) (context.Context, *controller) {
 	c := &controller{
 		queryStringMasks:         make(map[string]string),
@@ -260,6 +454,8 @@ func contextWithController(ctx context.Context, sdk *Speakeasy) (context.Context
 		responseCookieMasks:      make(map[string]string),
 		responseFieldMasksString: make(map[string]string),
 		responseFieldMasksNumber: make(map[string]string),
+		graphqlVariableMasks:     make(map[string]string),
+		metadata:                 make(map[string]string),
 		sdkInstance:              sdk,
 	}
 	return context.WithValue(ctx, controllerKey, c), c
@@ -0,0 +1,75 @@
+package speakeasy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CustomerIDFromHeader returns a CustomerIDResolver that reads the customer ID from a request header.
+func CustomerIDFromHeader(header string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// CustomerIDFromContext returns a CustomerIDResolver that reads the customer ID from a request context value,
+// the value must be a string or implement fmt.Stringer.
+func CustomerIDFromContext(key interface{}) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		switch v := r.Context().Value(key).(type) {
+		case string:
+			return v
+		case fmt.Stringer:
+			return v.String()
+		default:
+			return ""
+		}
+	}
+}
+
+// CustomerIDFromJWTClaim returns a CustomerIDResolver that reads the customer ID from a claim of the bearer JWT in the
+// Authorization header, for example "sub". The JWT is decoded but not verified, so it should already have been verified
+// by your authentication middleware.
+func CustomerIDFromJWTClaim(claim string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+
+		parts := strings.Split(strings.TrimSpace(token), ".")
+		if len(parts) != 3 {
+			return ""
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err != nil {
+			return ""
+		}
+
+		var claims map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return ""
+		}
+
+		value, ok := claims[claim]
+		if !ok {
+			return ""
+		}
+
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return s
+		}
+
+		var n json.Number
+		if err := json.Unmarshal(value, &n); err == nil {
+			return n.String()
+		}
+
+		return ""
+	}
+}
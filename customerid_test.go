@@ -0,0 +1,105 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+type customerIDContextKey struct{}
+
+type customerIDStringer struct{ id string }
+
+func (c customerIDStringer) String() string { return c.id }
+
+func TestCustomerIDResolvers(t *testing.T) {
+	jwt := func(payload string) string {
+		return "Bearer eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+
+	tests := []struct {
+		name           string
+		resolver       func(r *http.Request) string
+		headers        map[string]string
+		contextValue   interface{}
+		wantCustomerID string
+	}{
+		{
+			name:           "resolves customer id from header",
+			resolver:       speakeasy.CustomerIDFromHeader("X-Customer-ID"),
+			headers:        map[string]string{"X-Customer-ID": "customer-1"},
+			wantCustomerID: "customer-1",
+		},
+		{
+			name:           "resolves empty customer id from missing header",
+			resolver:       speakeasy.CustomerIDFromHeader("X-Customer-ID"),
+			wantCustomerID: "",
+		},
+		{
+			name:           "resolves customer id from context string",
+			resolver:       speakeasy.CustomerIDFromContext(customerIDContextKey{}),
+			contextValue:   "customer-1",
+			wantCustomerID: "customer-1",
+		},
+		{
+			name:           "resolves customer id from context stringer",
+			resolver:       speakeasy.CustomerIDFromContext(customerIDContextKey{}),
+			contextValue:   customerIDStringer{id: "customer-1"},
+			wantCustomerID: "customer-1",
+		},
+		{
+			name:           "resolves empty customer id from unsupported context value",
+			resolver:       speakeasy.CustomerIDFromContext(customerIDContextKey{}),
+			contextValue:   1234,
+			wantCustomerID: "",
+		},
+		{
+			name:           "resolves customer id from jwt string claim",
+			resolver:       speakeasy.CustomerIDFromJWTClaim("sub"),
+			headers:        map[string]string{"Authorization": jwt(`{"sub": "customer-1", "iat": 1516239022}`)},
+			wantCustomerID: "customer-1",
+		},
+		{
+			name:           "resolves customer id from jwt number claim",
+			resolver:       speakeasy.CustomerIDFromJWTClaim("org_id"),
+			headers:        map[string]string{"Authorization": jwt(`{"sub": "user-1", "org_id": 12345}`)},
+			wantCustomerID: "12345",
+		},
+		{
+			name:           "resolves empty customer id from missing jwt claim",
+			resolver:       speakeasy.CustomerIDFromJWTClaim("org_id"),
+			headers:        map[string]string{"Authorization": jwt(`{"sub": "user-1"}`)},
+			wantCustomerID: "",
+		},
+		{
+			name:           "resolves empty customer id from non bearer authorization",
+			resolver:       speakeasy.CustomerIDFromJWTClaim("sub"),
+			headers:        map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			wantCustomerID: "",
+		},
+		{
+			name:           "resolves empty customer id from malformed jwt",
+			resolver:       speakeasy.CustomerIDFromJWTClaim("sub"),
+			headers:        map[string]string{"Authorization": "Bearer not-a-jwt"},
+			wantCustomerID: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://test.com", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if tt.contextValue != nil {
+				r = r.WithContext(context.WithValue(r.Context(), customerIDContextKey{}, tt.contextValue))
+			}
+
+			assert.Equal(t, tt.wantCustomerID, tt.resolver(r))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package speakeasy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+)
+
+// GinMiddleware setups up the default SDK instance to start capturing requests from the gin http framework.
+//
+// Deprecated: use speakeasygin.Middleware from the github.com/speakeasy-api/speakeasy-go-sdk/speakeasygin module instead.
+// GinMiddleware will be removed in the next major version of the SDK.
+func GinMiddleware(c *gin.Context) {
+	defaultInstance.GinMiddleware(c)
+}
+
+// GinMiddleware setups the current instance of the SDK to start capturing requests from the gin http framework.
+//
+// Deprecated: use speakeasygin.New from the github.com/speakeasy-api/speakeasy-go-sdk/speakeasygin module instead.
+// GinMiddleware will be removed in the next major version of the SDK.
+func (s *Speakeasy) GinMiddleware(c *gin.Context) {
+	s.handleRequestResponse(c.Writer, c.Request, func(w http.ResponseWriter, r *http.Request) {
+		c.Writer = &ginResponseWriter{c.Writer, w}
+		c.Request = r
+
+		c.Next()
+
+		// Errors collected by gin aren't returned, so they are recorded here to be attached to the capture
+		if ctrl, ok := MiddlewareController(r); ok {
+			ctrl.setRequest(c.Request)
+
+			for _, err := range c.Errors {
+				ctrl.RecordError(err.Err)
+			}
+		}
+	}, func(c *gin.Context) func(r *http.Request) string {
+		return func(r *http.Request) string {
+			pathHint := s.MatchOpenAPIPath(r)
+			if pathHint != "" {
+				return pathHint
+			}
+
+			return c.FullPath()
+		}
+	}(c))
+}
+
+// EchoMiddleware setups up the default SDK instance to start capturing requests from the echo http framework.
+//
+// Deprecated: use speakeasyecho.Middleware from the github.com/speakeasy-api/speakeasy-go-sdk/speakeasyecho module instead.
+// EchoMiddleware will be removed in the next major version of the SDK.
+func EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return defaultInstance.EchoMiddleware(next)
+}
+
+// EchoMiddleware setups the current instance of the SDK to start capturing requests from the echo http framework.
+//
+// Deprecated: use speakeasyecho.New from the github.com/speakeasy-api/speakeasy-go-sdk/speakeasyecho module instead.
+// EchoMiddleware will be removed in the next major version of the SDK.
+func (s *Speakeasy) EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return s.handleRequestResponseError(c.Response(), c.Request(), func(w http.ResponseWriter, r *http.Request) error {
+			c.SetResponse(echo.NewResponse(w, c.Echo()))
+			c.SetRequest(r)
+
+			// Echo's error handler writes the response for an error once the handler chain has returned, so it is invoked here
+			// (as echo's own middleware does) to allow the response it writes to be captured. The error is still returned, as
+			// echo's error handler skips responses that have already been committed.
+			err := next(c)
+
+			ctrl, ok := MiddlewareController(r)
+			if ok {
+				ctrl.setRequest(c.Request())
+			}
+
+			if err != nil {
+				if ok {
+					ctrl.RecordError(err)
+				}
+
+				c.Error(err)
+			}
+
+			return err
+		}, func(r *http.Request) string {
+			pathHint := s.MatchOpenAPIPath(r)
+			if pathHint != "" {
+				return pathHint
+			}
+
+			return c.Path()
+		})
+	}
+}
+
+// resolveRouterPathHint resolves the path hint of a request served by a gorilla/mux or go-chi/chi router.
+//
+// Deprecated: these routers are resolved by the PathHintResolvers of the speakeasymux and speakeasychi modules, the Middleware
+// will stop resolving them itself in the next major version of the SDK.
+func resolveRouterPathHint(r *http.Request) string {
+	// Check gorilla/mux for a path hint
+	route := mux.CurrentRoute(r)
+	if route != nil {
+		pathHint, _ := route.GetPathTemplate()
+		if pathHint != "" {
+			return pathHint
+		}
+	}
+
+	// Check chi router for a path hint
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext != nil {
+		pathHint := routeContext.RoutePattern()
+		if pathHint != "" {
+			return pathHint
+		}
+	}
+
+	return ""
+}
+
+type ginResponseWriter struct {
+	gin.ResponseWriter
+	writer http.ResponseWriter
+}
+
+var _ gin.ResponseWriter = &ginResponseWriter{}
+
+func (g *ginResponseWriter) Write(data []byte) (int, error) {
+	return g.writer.Write(data)
+}
+
+func (g *ginResponseWriter) WriteString(s string) (int, error) {
+	return g.writer.Write([]byte(s))
+}
+
+func (g *ginResponseWriter) WriteHeader(statusCode int) {
+	g.writer.WriteHeader(statusCode)
+}
+
+func (g *ginResponseWriter) Flush() {
+	if f, ok := g.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *ginResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := g.writer.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return g.ResponseWriter.Hijack()
+}
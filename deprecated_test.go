@@ -0,0 +1,658 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeakeasy_Middleware_GorillaMux_PathHint_Success(t *testing.T) {
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from mux",
+			args: args{
+				path: "/user/{id}",
+				url:  "http://test.com/user/1",
+			},
+			wantPathHint: "/user/{id}",
+		},
+		{
+			name: "captures more complex path hint from mux",
+			args: args{
+				path: "/user/{id:[0-9]+}/account/{accountID}",
+				url:  "http://test.com/user/1/account/abcdefg",
+			},
+			wantPathHint: "/user/{id}/account/{accountID}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/{id:[0-9]+}/account/{accountID}",
+				url:     "http://test.com/user/1/account/abcdefg",
+				devHint: "/user/{id}/account/{accountID}",
+			},
+			wantPathHint: "/user/{id}/account/{accountID}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+			handled := false
+
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := mux.NewRouter()
+			r.Use(sdkInstance.Middleware)
+
+			r.Methods(http.MethodGet).Path(tt.args.path).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(req)
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+
+				w.WriteHeader(http.StatusOK)
+				handled = true
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			assert.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestSpeakeasy_Middleware_Chi_PathHint_Success(t *testing.T) {
+	type args struct {
+		path string
+		url  string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from chi",
+			args: args{
+				path: "/user/{id}",
+				url:  "http://test.com/user/1",
+			},
+			wantPathHint: "/user/{id}",
+		},
+		{
+			name: "captures complex path hint from chi",
+			args: args{
+				path: "/user/{id}/account/*",
+				url:  "http://test.com/user/abcdefg/account/1",
+			},
+			wantPathHint: "/user/{id}/account/{wildcard}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+			handled := false
+
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := chi.NewRouter()
+			r.Use(sdkInstance.Middleware)
+
+			r.Get(tt.args.path, func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				handled = true
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			assert.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestSpeakeasy_GinMiddleware_Success(t *testing.T) {
+	tests := speakeasytest.LoadGoldenTests(t, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tt.Setup(t)
+
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.JSONEq(t, tt.WantHAR, req.Har)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := gin.Default()
+			r.Use(sdkInstance.GinMiddleware)
+
+			r.Any("/*path", func(ctx *gin.Context) {
+				ctrl, _ := speakeasy.MiddlewareController(ctx.Request)
+
+				ctrl.Masking(tt.MaskingOptions()...)
+
+				for _, header := range tt.Args.ResponseHeaders {
+					for _, val := range header.Values {
+						ctx.Writer.Header().Add(header.Key, val)
+					}
+				}
+
+				if ctx.Request.Body != nil {
+					data, err := io.ReadAll(ctx.Request.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.Args.Body, string(data))
+				}
+
+				if tt.Args.ResponseStatus > 0 {
+					ctx.Writer.WriteHeader(tt.Args.ResponseStatus)
+				}
+
+				if tt.Args.ResponseBody != "" {
+					_, err := ctx.Writer.Write([]byte(tt.Args.ResponseBody))
+					assert.NoError(t, err)
+				}
+				handled = true
+			})
+
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, tt.NewRequest(t))
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+
+			assert.Equal(t, tt.WantResponseStatus(), w.Code)
+		})
+	}
+}
+
+func TestSpeakeasy_GinMiddleware_PathHint_Success(t *testing.T) {
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from gin",
+			args: args{
+				path: "/user",
+				url:  "http://test.com/user",
+			},
+			wantPathHint: "/user",
+		},
+		{
+			name: "captures more complex path hint from gin",
+			args: args{
+				path: "/user/:id/*action",
+				url:  "http://test.com/user/1/send",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/:id/*action",
+				url:     "http://test.com/user/1/sent",
+				devHint: "/user/{id}/{action}",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+			handled := false
+
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := gin.Default()
+			r.Use(sdkInstance.GinMiddleware)
+
+			r.Handle(http.MethodGet, tt.args.path, func(ctx *gin.Context) {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(ctx.Request)
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+				ctx.Writer.WriteHeader(http.StatusOK)
+				handled = true
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			assert.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestSpeakeasy_EchoMiddleware_Success(t *testing.T) {
+	tests := speakeasytest.LoadGoldenTests(t, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tt.Setup(t)
+
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.JSONEq(t, tt.WantHAR, req.Har)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := echo.New()
+			r.Use(sdkInstance.EchoMiddleware)
+
+			r.Any("/*", func(c echo.Context) error {
+				ctrl, _ := speakeasy.MiddlewareController(c.Request())
+
+				ctrl.Masking(tt.MaskingOptions()...)
+
+				for _, header := range tt.Args.ResponseHeaders {
+					for _, val := range header.Values {
+						c.Response().Header().Add(header.Key, val)
+					}
+				}
+
+				if c.Request().Body != nil {
+					data, err := io.ReadAll(c.Request().Body)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.Args.Body, string(data))
+				}
+
+				if tt.Args.ResponseStatus > 0 {
+					c.Response().WriteHeader(tt.Args.ResponseStatus)
+				}
+
+				if tt.Args.ResponseBody != "" {
+					_, err := c.Response().Write([]byte(tt.Args.ResponseBody))
+					assert.NoError(t, err)
+				}
+				handled = true
+
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, tt.NewRequest(t))
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+
+			assert.Equal(t, tt.WantResponseStatus(), w.Code)
+		})
+	}
+}
+
+func TestSpeakeasy_EchoMiddleware_PathHint_Success(t *testing.T) {
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from echo",
+			args: args{
+				path: "/user",
+				url:  "http://test.com/user",
+			},
+			wantPathHint: "/user",
+		},
+		{
+			name: "captures more complex path hint from echo",
+			args: args{
+				path: "/user/:id/*",
+				url:  "http://test.com/user/1/send",
+			},
+			wantPathHint: "/user/{id}/{wildcard}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/:id/*action",
+				url:     "http://test.com/user/1/sent",
+				devHint: "/user/{id}/{action}",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+			handled := false
+
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := echo.New()
+			r.Use(sdkInstance.EchoMiddleware)
+			r.Match([]string{http.MethodGet}, tt.args.path, func(c echo.Context) error {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(c.Request())
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+				c.Response().Writer.WriteHeader(http.StatusOK)
+				handled = true
+
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			assert.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+type handlerErrorEntry struct {
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"response"`
+	Errors []struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"_errors"`
+}
+
+func handlerErrorDialer(t *testing.T, entry *handlerErrorEntry) func() func(context.Context, string) (net.Conn, error) {
+	t.Helper()
+
+	return dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+		var h struct {
+			Log struct {
+				Entries []handlerErrorEntry `json:"entries"`
+			} `json:"log"`
+		}
+
+		err := json.Unmarshal([]byte(req.GetHar()), &h)
+		require.NoError(t, err)
+
+		*entry = h.Log.Entries[0]
+	})
+}
+
+func TestSpeakeasy_EchoMiddleware_HandlerError_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	var entry handlerErrorEntry
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: handlerErrorDialer(t, &entry),
+	})
+
+	var returnedErr error
+
+	r := echo.New()
+	// the error should be returned to the middleware before the speakeasy middleware
+	r.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			returnedErr = next(c)
+			return returnedErr
+		}
+	})
+	r.Use(sdkInstance.EchoMiddleware)
+	r.GET("/test", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"message": "user not found"}`, w.Body.String())
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, returnedErr, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+
+	assert.Equal(t, http.StatusNotFound, entry.Response.Status)
+	assert.JSONEq(t, `{"message": "user not found"}`, entry.Response.Content.Text)
+	require.Len(t, entry.Errors, 1)
+	assert.Equal(t, "code=404, message=user not found", entry.Errors[0].Message)
+	assert.Equal(t, "*echo.HTTPError", entry.Errors[0].Type)
+}
+
+func TestSpeakeasy_GinMiddleware_HandlerError_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	var entry handlerErrorEntry
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: handlerErrorDialer(t, &entry),
+	})
+
+	r := gin.Default()
+	r.Use(sdkInstance.GinMiddleware)
+	r.GET("/test", func(c *gin.Context) {
+		_ = c.Error(io.ErrUnexpectedEOF)
+		_ = c.Error(&json.SyntaxError{})
+		c.String(http.StatusBadRequest, "invalid request")
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, http.StatusBadRequest, entry.Response.Status)
+	assert.Equal(t, "invalid request", entry.Response.Content.Text)
+	require.Len(t, entry.Errors, 2)
+	assert.Equal(t, "unexpected EOF", entry.Errors[0].Message)
+	assert.Equal(t, "*errors.errorString", entry.Errors[0].Type)
+	assert.Equal(t, "*json.SyntaxError", entry.Errors[1].Type)
+}
+
+func TestSpeakeasy_GinMiddleware_CustomerIDResolver_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	type contextKey struct{}
+
+	var capturedCustomerID string
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			capturedCustomerID = req.CustomerId
+		}),
+		CustomerIDResolver: speakeasy.CustomerIDFromContext(contextKey{}),
+	})
+
+	r := gin.Default()
+	r.Use(sdkInstance.GinMiddleware)
+	// authentication middleware running after the speakeasy middleware
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, "an-authenticated-customer"))
+		c.Next()
+	})
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "an-authenticated-customer", capturedCustomerID)
+}
@@ -0,0 +1,161 @@
+package speakeasy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RequestFilter matches requests by their path, method, host or user agent. A filter matches a request if all of its
+// conditions match, a condition matches if any of its values match and conditions that aren't set are ignored.
+type RequestFilter struct {
+	// Paths are glob patterns (as supported by path.Match) matched against the request path, for example "/internal/*".
+	Paths []string
+	// PathRegexes are regular expressions matched against the request path.
+	PathRegexes []string
+	// Methods are matched against the request method, for example http.MethodOptions.
+	Methods []string
+	// Hosts are glob patterns (as supported by path.Match) matched against the request host without its port.
+	Hosts []string
+	// UserAgents are regular expressions matched against the request User-Agent header.
+	UserAgents []string
+}
+
+type requestFilter struct {
+	paths       []string
+	pathRegexes []*regexp.Regexp
+	methods     []string
+	hosts       []string
+	userAgents  []*regexp.Regexp
+}
+
+func mustCompileFilters(filters []RequestFilter) []requestFilter {
+	compiled := make([]requestFilter, 0, len(filters))
+
+	for _, f := range filters {
+		for _, pattern := range append(append([]string{}, f.Paths...), f.Hosts...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				panic(fmt.Errorf("invalid glob pattern %q: %w", pattern, ErrRequestFilterMalformed))
+			}
+		}
+
+		compiled = append(compiled, requestFilter{
+			paths:       f.Paths,
+			pathRegexes: mustCompileRegexes(f.PathRegexes),
+			methods:     f.Methods,
+			hosts:       f.Hosts,
+			userAgents:  mustCompileRegexes(f.UserAgents),
+		})
+	}
+
+	return compiled
+}
+
+func mustCompileRegexes(exprs []string) []*regexp.Regexp {
+	regexes := make([]*regexp.Regexp, 0, len(exprs))
+
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			panic(fmt.Errorf("invalid regular expression %q: %w", expr, ErrRequestFilterMalformed))
+		}
+
+		regexes = append(regexes, re)
+	}
+
+	return regexes
+}
+
+func (s *Speakeasy) shouldCapture(r *http.Request) bool {
+	if len(s.includeFilters) > 0 && !matchesAnyFilter(s.includeFilters, r) {
+		return false
+	}
+
+	if matchesAnyFilter(s.excludeFilters, r) {
+		return false
+	}
+
+	if s.config.ShouldCapture != nil {
+		return s.config.ShouldCapture(r)
+	}
+
+	return true
+}
+
+func matchesAnyFilter(filters []requestFilter, r *http.Request) bool {
+	for _, f := range filters {
+		if f.matches(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f requestFilter) matches(r *http.Request) bool {
+	if len(f.paths) == 0 && len(f.pathRegexes) == 0 && len(f.methods) == 0 && len(f.hosts) == 0 && len(f.userAgents) == 0 {
+		return false
+	}
+
+	if len(f.paths) > 0 && !matchesAnyGlob(f.paths, r.URL.Path) {
+		return false
+	}
+
+	if len(f.pathRegexes) > 0 && !matchesAnyRegex(f.pathRegexes, r.URL.Path) {
+		return false
+	}
+
+	if len(f.methods) > 0 {
+		matched := false
+		for _, method := range f.methods {
+			if strings.EqualFold(method, r.Method) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.hosts) > 0 {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if !matchesAnyGlob(f.hosts, strings.ToLower(host)) {
+			return false
+		}
+	}
+
+	if len(f.userAgents) > 0 && !matchesAnyRegex(f.userAgents, r.UserAgent()) {
+		return false
+	}
+
+	return true
+}
+
+func matchesAnyGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnyRegex(regexes []*regexp.Regexp, value string) bool {
+	for _, re := range regexes {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
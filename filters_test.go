@@ -0,0 +1,178 @@
+//nolint:testpackage
+package speakeasy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpeakeasy_shouldCapture(t *testing.T) {
+	type args struct {
+		method    string
+		url       string
+		userAgent string
+	}
+	tests := []struct {
+		name          string
+		include       []RequestFilter
+		exclude       []RequestFilter
+		shouldCapture func(r *http.Request) bool
+		args          args
+		wantCapture   bool
+	}{
+		{
+			name:        "captures all requests without filters",
+			args:        args{method: http.MethodGet, url: "http://test.com/healthz"},
+			wantCapture: true,
+		},
+		{
+			name:        "excludes requests matching path glob",
+			exclude:     []RequestFilter{{Paths: []string{"/healthz", "/internal/*"}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/internal/metrics"},
+			wantCapture: false,
+		},
+		{
+			name:        "captures requests not matching path glob",
+			exclude:     []RequestFilter{{Paths: []string{"/healthz", "/internal/*"}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/internal/metrics/cpu"},
+			wantCapture: true,
+		},
+		{
+			name:        "excludes requests matching path regex",
+			exclude:     []RequestFilter{{PathRegexes: []string{`^/metrics(/.*)?$`}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/metrics/cpu"},
+			wantCapture: false,
+		},
+		{
+			name:        "excludes requests matching method",
+			exclude:     []RequestFilter{{Methods: []string{http.MethodOptions}}},
+			args:        args{method: http.MethodOptions, url: "http://test.com/user"},
+			wantCapture: false,
+		},
+		{
+			name:        "excludes requests matching host without port",
+			exclude:     []RequestFilter{{Hosts: []string{"*.internal"}}},
+			args:        args{method: http.MethodGet, url: "http://api.internal:8080/user"},
+			wantCapture: false,
+		},
+		{
+			name:        "excludes requests matching user agent",
+			exclude:     []RequestFilter{{UserAgents: []string{`(?i)kube-probe`}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/user", userAgent: "kube-probe/1.27"},
+			wantCapture: false,
+		},
+		{
+			name:        "requires all conditions of a filter to match",
+			exclude:     []RequestFilter{{Paths: []string{"/user"}, Methods: []string{http.MethodOptions}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/user"},
+			wantCapture: true,
+		},
+		{
+			name:        "ignores empty filters",
+			exclude:     []RequestFilter{{}},
+			args:        args{method: http.MethodGet, url: "http://test.com/user"},
+			wantCapture: true,
+		},
+		{
+			name:        "captures requests matching include filter",
+			include:     []RequestFilter{{Paths: []string{"/v1/*"}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/v1/user"},
+			wantCapture: true,
+		},
+		{
+			name:        "doesn't capture requests not matching include filter",
+			include:     []RequestFilter{{Paths: []string{"/v1/*"}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/v2/user"},
+			wantCapture: false,
+		},
+		{
+			name:        "exclude filters take precedence over include filters",
+			include:     []RequestFilter{{Paths: []string{"/v1/*"}}},
+			exclude:     []RequestFilter{{Methods: []string{http.MethodOptions}}},
+			args:        args{method: http.MethodOptions, url: "http://test.com/v1/user"},
+			wantCapture: false,
+		},
+		{
+			name: "doesn't capture requests rejected by callback",
+			shouldCapture: func(r *http.Request) bool {
+				return r.Header.Get("User-Agent") != "internal"
+			},
+			args:        args{method: http.MethodGet, url: "http://test.com/user", userAgent: "internal"},
+			wantCapture: false,
+		},
+		{
+			name: "callback isn't called for excluded requests",
+			shouldCapture: func(r *http.Request) bool {
+				panic("unexpected call")
+			},
+			exclude:     []RequestFilter{{Paths: []string{"/healthz"}}},
+			args:        args{method: http.MethodGet, url: "http://test.com/healthz"},
+			wantCapture: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Speakeasy{
+				config:         Config{ShouldCapture: tt.shouldCapture},
+				includeFilters: mustCompileFilters(tt.include),
+				excludeFilters: mustCompileFilters(tt.exclude),
+			}
+
+			r := httptest.NewRequest(tt.args.method, tt.args.url, nil)
+			if tt.args.userAgent != "" {
+				r.Header.Set("User-Agent", tt.args.userAgent)
+			}
+
+			assert.Equal(t, tt.wantCapture, s.shouldCapture(r))
+		})
+	}
+}
+
+func TestMustCompileFilters_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []RequestFilter
+		wantErr string
+	}{
+		{
+			name:    "invalid path glob",
+			filters: []RequestFilter{{Paths: []string{"/user/["}}},
+			wantErr: `invalid glob pattern "/user/["`,
+		},
+		{
+			name:    "invalid host glob",
+			filters: []RequestFilter{{Hosts: []string{"[a-"}}},
+			wantErr: `invalid glob pattern "[a-"`,
+		},
+		{
+			name:    "invalid path regex",
+			filters: []RequestFilter{{PathRegexes: []string{"/user/(.*"}}},
+			wantErr: `invalid regular expression "/user/(.*"`,
+		},
+		{
+			name:    "invalid user agent regex",
+			filters: []RequestFilter{{UserAgents: []string{"*curl"}}},
+			wantErr: `invalid regular expression "*curl"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+
+				err, ok := r.(error)
+				if assert.True(t, ok) {
+					assert.True(t, errors.Is(err, ErrRequestFilterMalformed))
+					assert.True(t, strings.HasPrefix(err.Error(), tt.wantErr))
+				}
+			}()
+
+			mustCompileFilters(tt.filters)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package speakeasy
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/bodymasking"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/graphql"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
+)
+
+const defaultGraphQLPath = "/graphql"
+
+// GraphQLConfig configures the capture of GraphQL requests, which are given path hints for their operation rather than the
+// single path all GraphQL requests are served on.
+type GraphQLConfig struct {
+	// Paths are the paths GraphQL requests are served on, if not provided "/graphql" is used.
+	Paths []string
+}
+
+// graphqlOperation records the GraphQL operation executed by a request.
+type graphqlOperation struct {
+	OperationType string                     `json:"operationType"`
+	OperationName string                     `json:"operationName,omitempty"`
+	Variables     map[string]json.RawMessage `json:"variables,omitempty"`
+}
+
+// resolveGraphQLOperation parses the GraphQL operation from a captured request body, returning nil if the request
+// isn't a GraphQL request made to one of the configured paths.
+func (s *Speakeasy) resolveGraphQLOperation(r *http.Request, cw *captureWriter) *graphqlOperation {
+	cfg := s.config.GraphQL
+	if cfg == nil || r.Method != http.MethodPost || !cw.IsReqValid() {
+		return nil
+	}
+
+	isGraphQLPath := false
+
+	for _, p := range cfg.Paths {
+		if strings.TrimSuffix(r.URL.Path, "/") == strings.TrimSuffix(p, "/") {
+			isGraphQLPath = true
+			break
+		}
+	}
+
+	if !isGraphQLPath {
+		return nil
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+			return nil
+		}
+	}
+
+	req, ok := graphql.ParseRequest(cw.GetReqBuffer().Bytes())
+	if !ok {
+		return nil
+	}
+
+	op, ok := req.Operation()
+	if !ok {
+		return nil
+	}
+
+	return &graphqlOperation{
+		OperationType: op.Type,
+		OperationName: op.Name,
+		Variables:     req.Variables,
+	}
+}
+
+// getGraphQLPathHint appends the type and name of the operation to the path hint of a GraphQL request,
+// for example "/graphql/query/GetUser". The path of the request is used if there is no path hint from a router.
+func getGraphQLPathHint(pathHint string, r *http.Request, op *graphqlOperation) string {
+	if pathHint == "" {
+		pathHint = r.URL.Path
+	}
+
+	pathHint = strings.TrimSuffix(pathHint, "/") + "/" + op.OperationType
+
+	// The operation name is chosen by the client, so names that can't be used in a path hint are captured as anonymous operations
+	if op.OperationName != "" && pathhints.IsSafeSegment(op.OperationName) {
+		pathHint += "/" + op.OperationName
+	}
+
+	return pathHint
+}
+
+// maskGraphQLVariableFields returns the variables of the operation with any fields, including nested fields, masked by the
+// request field masks as they are in the request body.
+func maskGraphQLVariableFields(variables map[string]json.RawMessage, stringMasks, numberMasks map[string]string) (map[string]json.RawMessage, error) {
+	if len(variables) == 0 || (len(stringMasks) == 0 && len(numberMasks) == 0) {
+		return variables, nil
+	}
+
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	maskedData, err := bodymasking.MaskBodyRegex(string(data), "application/json", stringMasks, numberMasks)
+	if err != nil {
+		return nil, err
+	}
+
+	masked := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(maskedData), &masked); err != nil {
+		return nil, err
+	}
+
+	return masked, nil
+}
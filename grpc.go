@@ -0,0 +1,466 @@
+package speakeasy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/hooks"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/log"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// binaryMetadataSuffix is the suffix of metadata keys with binary values.
+const binaryMetadataSuffix = "-bin"
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor using the default SDK instance to capture unary calls.
+// See Speakeasy.UnaryServerInterceptor for details.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return defaultInstance.UnaryServerInterceptor()
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor using the default SDK instance to capture streaming calls.
+// See Speakeasy.StreamServerInterceptor for details.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return defaultInstance.StreamServerInterceptor()
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor using the current instance of the SDK to capture unary calls.
+// Calls are captured as POST requests to the full method name, which is used as the path hint, with the incoming metadata as
+// request headers and the request and response messages rendered as JSON. The gRPC status of the call is captured as the
+// response status, mapped to its equivalent HTTP status, and in the grpc-status and grpc-message trailers.
+func (s *Speakeasy) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// The messages are only rendered once the call is known to be captured
+		body := &grpcMessageReader{ctx: ctx, msg: req}
+
+		var res interface{}
+
+		err := s.handleRequestResponseError(&outboundResponseWriter{header: http.Header{}}, newGRPCRequest(ctx, info.FullMethod, body), func(w http.ResponseWriter, r *http.Request) error {
+			// Calls that aren't captured, as they were filtered or not sampled, are handled without rendering their messages
+			if !isRecording(r) {
+				var err error
+				res, err = handler(r.Context(), req)
+
+				return err
+			}
+
+			// The request message has already been received so its body is read before the handler is called
+			//nolint:errcheck
+			io.Copy(io.Discard, r.Body)
+
+			ctx := r.Context()
+			if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
+				ctx = grpc.NewContextWithServerTransportStream(ctx, &grpcTransportStream{ServerTransportStream: stream, header: w.Header()})
+			}
+
+			var err error
+			res, err = handler(ctx, req)
+
+			resBody := &limitedBuffer{limit: hooks.MaxCaptureSize}
+			if err == nil {
+				writeGRPCMessage(ctx, resBody, res)
+			}
+
+			writeGRPCResponse(w, r, err, resBody)
+
+			return err
+		}, func(r *http.Request) string {
+			return info.FullMethod
+		})
+
+		return res, err
+	}
+}
+
+// StreamServerInterceptor returns a grpc.StreamServerInterceptor using the current instance of the SDK to capture streaming calls.
+// Calls are captured as for UnaryServerInterceptor, with the messages received and sent on the stream rendered as JSON arrays.
+// The call is captured once the handler returns.
+func (s *Speakeasy) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		body := &bytes.Buffer{}
+
+		return s.handleRequestResponseError(&outboundResponseWriter{header: http.Header{}}, newGRPCRequest(ss.Context(), info.FullMethod, body), func(w http.ResponseWriter, r *http.Request) error {
+			// Messages of calls that aren't captured are passed through without being rendered
+			if !isRecording(r) {
+				return handler(srv, &contextServerStream{ServerStream: ss, ctx: r.Context()})
+			}
+
+			resBody := &limitedBuffer{limit: hooks.MaxCaptureSize}
+
+			stream := &capturedServerStream{
+				ServerStream: ss,
+				ctx:          r.Context(),
+				header:       w.Header(),
+				req:          &grpcMessageArray{w: body},
+				reqBody:      r.Body,
+				res:          &grpcMessageArray{w: resBody},
+			}
+
+			err := handler(srv, stream)
+
+			stream.req.close()
+			//nolint:errcheck
+			io.Copy(io.Discard, r.Body)
+
+			stream.res.close()
+
+			writeGRPCResponse(w, r, err, resBody)
+
+			return err
+		}, func(r *http.Request) string {
+			return info.FullMethod
+		})
+	}
+}
+
+// isRecording reports whether the call is recorded to be captured, rather than filtered or not sampled.
+func isRecording(r *http.Request) bool {
+	c, ok := MiddlewareController(r)
+	return ok && c.Recording()
+}
+
+// newGRPCRequest creates the request a gRPC call is captured as, with its incoming metadata as headers.
+func newGRPCRequest(ctx context.Context, fullMethod string, body io.Reader) *http.Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	header := http.Header{}
+	host := ""
+
+	for key, values := range md {
+		// Pseudo-headers aren't headers of the request, but the authority is its host
+		if strings.HasPrefix(key, ":") {
+			if key == ":authority" && len(values) > 0 {
+				host = values[0]
+			}
+
+			continue
+		}
+
+		for _, value := range values {
+			// Binary metadata is base64 encoded on the wire
+			if strings.HasSuffix(key, binaryMetadataSuffix) {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+
+			header.Add(key, value)
+		}
+	}
+
+	// Messages are captured as JSON so they can be masked
+	header.Set("Content-Type", "application/json")
+
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Host: host, Path: fullMethod},
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     header,
+		Body:       io.NopCloser(body),
+		Host:       host,
+		RequestURI: fullMethod,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := info.State
+			r.TLS = &state
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+// writeGRPCResponse writes the captured response of a gRPC call, with its status and messages.
+func writeGRPCResponse(w http.ResponseWriter, r *http.Request, err error, body *limitedBuffer) {
+	st := status.Convert(err)
+
+	if c, ok := ControllerFromContext(r.Context()); ok {
+		c.statusText = st.Code().String()
+
+		if err != nil {
+			c.RecordError(err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(st.Code())))
+
+	if st.Message() != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", st.Message())
+	}
+
+	w.WriteHeader(httpStatusFromCode(st.Code()))
+
+	//nolint:errcheck
+	w.Write(body.Bytes())
+}
+
+// writeGRPCMessage renders a message as JSON, using protojson for protobuf messages.
+func writeGRPCMessage(ctx context.Context, w io.Writer, msg interface{}) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if m, ok := msg.(proto.Message); ok {
+		data, err = protojson.Marshal(m)
+	} else {
+		data, err = json.Marshal(msg)
+	}
+
+	if err != nil {
+		log.From(ctx).Error("speakeasy-sdk: failed to marshal grpc message", zap.Error(err))
+		return
+	}
+
+	//nolint:errcheck
+	w.Write(data)
+}
+
+// grpcMessageArray renders the messages of a stream as a JSON array.
+type grpcMessageArray struct {
+	w     io.Writer
+	count int
+}
+
+func (a *grpcMessageArray) write(ctx context.Context, msg interface{}) {
+	separator := ","
+	if a.count == 0 {
+		separator = "["
+	}
+
+	//nolint:errcheck
+	io.WriteString(a.w, separator)
+
+	writeGRPCMessage(ctx, a.w, msg)
+
+	a.count++
+}
+
+func (a *grpcMessageArray) close() {
+	if a.count == 0 {
+		//nolint:errcheck
+		io.WriteString(a.w, "[")
+	}
+
+	//nolint:errcheck
+	io.WriteString(a.w, "]")
+}
+
+// contextServerStream provides the context of a call to its handler, so the controller can be used by calls that aren't captured.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// capturedServerStream records the messages and metadata of a streaming call.
+type capturedServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context //nolint:containedctx
+	header  http.Header
+	req     *grpcMessageArray
+	reqBody io.Reader
+	res     *grpcMessageArray
+}
+
+func (s *capturedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *capturedServerStream) SetHeader(md metadata.MD) error {
+	if err := s.ServerStream.SetHeader(md); err != nil {
+		return err
+	}
+
+	addGRPCMetadata(s.header, md, "")
+
+	return nil
+}
+
+func (s *capturedServerStream) SendHeader(md metadata.MD) error {
+	if err := s.ServerStream.SendHeader(md); err != nil {
+		return err
+	}
+
+	addGRPCMetadata(s.header, md, "")
+
+	return nil
+}
+
+func (s *capturedServerStream) SetTrailer(md metadata.MD) {
+	s.ServerStream.SetTrailer(md)
+
+	addGRPCMetadata(s.header, md, http.TrailerPrefix)
+}
+
+func (s *capturedServerStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.res.write(s.ctx, m)
+
+	return nil
+}
+
+func (s *capturedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.req.write(s.ctx, m)
+
+	// Reading the body records the message as it is received
+	//nolint:errcheck
+	io.Copy(io.Discard, s.reqBody)
+
+	return nil
+}
+
+// grpcTransportStream records the metadata sent by a unary call.
+type grpcTransportStream struct {
+	grpc.ServerTransportStream
+	header http.Header
+}
+
+func (s *grpcTransportStream) SetHeader(md metadata.MD) error {
+	if err := s.ServerTransportStream.SetHeader(md); err != nil {
+		return err
+	}
+
+	addGRPCMetadata(s.header, md, "")
+
+	return nil
+}
+
+func (s *grpcTransportStream) SendHeader(md metadata.MD) error {
+	if err := s.ServerTransportStream.SendHeader(md); err != nil {
+		return err
+	}
+
+	addGRPCMetadata(s.header, md, "")
+
+	return nil
+}
+
+func (s *grpcTransportStream) SetTrailer(md metadata.MD) error {
+	if err := s.ServerTransportStream.SetTrailer(md); err != nil {
+		return err
+	}
+
+	addGRPCMetadata(s.header, md, http.TrailerPrefix)
+
+	return nil
+}
+
+func addGRPCMetadata(header http.Header, md metadata.MD, prefix string) {
+	for key, values := range md {
+		for _, value := range values {
+			if strings.HasSuffix(key, binaryMetadataSuffix) {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+
+			header.Add(prefix+key, value)
+		}
+	}
+}
+
+// grpcMessageReader renders a message as JSON when it is first read, keeping no more of it than can be captured.
+type grpcMessageReader struct {
+	ctx context.Context //nolint:containedctx
+	msg interface{}
+	buf *limitedBuffer
+}
+
+func (r *grpcMessageReader) Read(p []byte) (int, error) {
+	if r.buf == nil {
+		r.buf = &limitedBuffer{limit: hooks.MaxCaptureSize}
+		writeGRPCMessage(r.ctx, r.buf, r.msg)
+	}
+
+	return r.buf.Read(p)
+}
+
+// limitedBuffer buffers up to one byte more than its limit, which is enough for the captureWriter to drop a body exceeding it.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit + 1 - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// httpStatusFromCode maps a gRPC status code to its equivalent HTTP status,
+// as documented in https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.Unknown:
+		return http.StatusInternalServerError
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Internal:
+		return http.StatusInternalServerError
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DataLoss:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,263 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type grpcCapture struct {
+	PathHint string
+	Entry    struct {
+		Request struct {
+			Method  string `json:"method"`
+			URL     string `json:"url"`
+			Headers []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"headers"`
+			PostData struct {
+				Text string `json:"text"`
+			} `json:"postData"`
+		} `json:"request"`
+		Response struct {
+			Status     int    `json:"status"`
+			StatusText string `json:"statusText"`
+			Headers    []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"headers"`
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"_errors"`
+	}
+}
+
+func (c grpcCapture) requestHeader(name string) string {
+	for _, h := range c.Entry.Request.Headers {
+		if h.Name == name {
+			return h.Value
+		}
+	}
+
+	return ""
+}
+
+func (c grpcCapture) responseHeader(name string) string {
+	for _, h := range c.Entry.Response.Headers {
+		if h.Name == name {
+			return h.Value
+		}
+	}
+
+	return ""
+}
+
+func newHealthClient(t *testing.T, captured chan<- grpcCapture) healthpb.HealthClient {
+	t.Helper()
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []json.RawMessage `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			c := grpcCapture{PathHint: req.PathHint}
+			err = json.Unmarshal(h.Log.Entries[0], &c.Entry)
+			require.NoError(t, err)
+
+			captured <- c
+		}),
+	})
+
+	masking := func(ctx context.Context) {
+		ctrl, ok := speakeasy.ControllerFromContext(ctx)
+		require.True(t, ok)
+
+		ctrl.Masking(speakeasy.WithRequestHeaderMask([]string{"Authorization"}))
+	}
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("test", healthpb.HealthCheckResponse_SERVING)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(sdkInstance.UnaryServerInterceptor(), func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			masking(ctx)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(sdkInstance.StreamServerInterceptor(), func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			masking(ss.Context())
+			return handler(srv, ss)
+		}),
+	)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestSpeakeasy_UnaryServerInterceptor_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan grpcCapture, 1)
+
+	client := newHealthClient(t, captured)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret", "x-trace-bin", "\x01\x02")
+
+	res, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	c := <-captured
+
+	assert.Equal(t, "/grpc.health.v1.Health/Check", c.PathHint)
+	assert.Equal(t, "POST", c.Entry.Request.Method)
+	assert.Equal(t, "http://bufnet/grpc.health.v1.Health/Check", c.Entry.Request.URL)
+	assert.Equal(t, "__masked__", c.requestHeader("Authorization"))
+	assert.Equal(t, "AQI=", c.requestHeader("X-Trace-Bin"))
+	assert.JSONEq(t, `{"service": "test"}`, c.Entry.Request.PostData.Text)
+	assert.Equal(t, 200, c.Entry.Response.Status)
+	assert.Equal(t, "OK", c.Entry.Response.StatusText)
+	assert.Equal(t, "0", c.responseHeader("Grpc-Status"))
+	assert.JSONEq(t, `{"status": "SERVING"}`, c.Entry.Response.Content.Text)
+	assert.Empty(t, c.Entry.Errors)
+}
+
+func TestSpeakeasy_UnaryServerInterceptor_Error(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan grpcCapture, 1)
+
+	client := newHealthClient(t, captured)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	c := <-captured
+
+	assert.Equal(t, 404, c.Entry.Response.Status)
+	assert.Equal(t, "NotFound", c.Entry.Response.StatusText)
+	assert.Equal(t, "5", c.responseHeader("Grpc-Status"))
+	assert.Equal(t, "unknown service", c.responseHeader("Grpc-Message"))
+	assert.Empty(t, c.Entry.Response.Content.Text)
+	require.Len(t, c.Entry.Errors, 1)
+	assert.Contains(t, c.Entry.Errors[0].Message, "unknown service")
+}
+
+// countingMessage counts the number of times it is rendered as JSON.
+type countingMessage struct {
+	marshaled *int
+}
+
+func (m countingMessage) MarshalJSON() ([]byte, error) {
+	*m.marshaled++
+	return []byte(`{}`), nil
+}
+
+func TestSpeakeasy_UnaryServerInterceptor_NotCaptured(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			t.Error("call shouldn't be captured")
+		}),
+		ShouldCapture: func(r *http.Request) bool {
+			return false
+		},
+	})
+
+	marshaled := 0
+
+	res, err := sdkInstance.UnaryServerInterceptor()(context.Background(), countingMessage{marshaled: &marshaled}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, ok := speakeasy.ControllerFromContext(ctx)
+		assert.True(t, ok)
+
+		return countingMessage{marshaled: &marshaled}, nil
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, res)
+
+	// messages of calls that aren't captured are never rendered
+	assert.Equal(t, 0, marshaled)
+}
+
+func TestSpeakeasy_StreamServerInterceptor_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan grpcCapture, 1)
+
+	client := newHealthClient(t, captured)
+
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret"))
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "test"})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	// the call is captured once the handler returns, which it does once the client has cancelled the stream
+	cancel()
+
+	c := <-captured
+
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", c.PathHint)
+	assert.Equal(t, "__masked__", c.requestHeader("Authorization"))
+	assert.JSONEq(t, `[{"service": "test"}]`, c.Entry.Request.PostData.Text)
+	assert.Equal(t, 499, c.Entry.Response.Status)
+	assert.Equal(t, "Canceled", c.Entry.Response.StatusText)
+	assert.Equal(t, "1", c.responseHeader("Grpc-Status"))
+	assert.JSONEq(t, `[{"status": "SERVING"}]`, c.Entry.Response.Content.Text)
+}
@@ -3,45 +3,197 @@ package speakeasy
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/chromedp/cdproto/har"
 	"github.com/gorilla/handlers"
 	"github.com/speakeasy-api/speakeasy-go-sdk/internal/bodymasking"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/graphql"
 	"github.com/speakeasy-api/speakeasy-go-sdk/internal/log"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/servertiming"
 	"go.uber.org/zap"
 )
 
-type harBuilder struct{}
+type harBuilder struct {
+	// trustedProxies are the networks forwarded headers are honored from, if empty forwarded headers aren't honored from any client.
+	trustedProxies []*net.IPNet
+	// tags are added to the metadata of every captured request.
+	tags map[string]string
+}
 
-func (h *harBuilder) buildHarFile(ctx context.Context, cw *captureWriter, r *http.Request, startTime time.Time, c *controller) *har.HAR {
-	resolvedURL := getResolvedURL(r, c)
+// harFile is a HAR file that also contains custom fields for its entry, custom fields are prefixed with an underscore
+// as allowed by the HAR 1.2 spec http://www.softwareishard.com/blog/har-12-spec/#custom-fields
+type harFile struct {
+	*har.HAR
+	entryFields   map[string]interface{}
+	timingsFields map[string]interface{}
+}
 
-	return &har.HAR{
-		Log: &har.Log{
-			Version: "1.2",
-			Creator: &har.Creator{
-				Name:    sdkName,
-				Version: speakeasyVersion,
-			},
-			Comment: "request capture for " + resolvedURL.String(),
-			Entries: []*har.Entry{
-				{
-					StartedDateTime: startTime.Format(time.RFC3339Nano),
-					Time:            float64(timeSince(startTime).Milliseconds()),
-					Request:         h.getHarRequest(ctx, cw, r, c, resolvedURL),
-					Response:        h.getHarResponse(ctx, cw, r, startTime, c),
-					Connection:      resolvedURL.Port(),
-					ServerIPAddress: resolvedURL.Hostname(),
-					Cache:           &har.Cache{},
-					Timings: &har.Timings{
-						Send:    -1,
-						Wait:    -1,
-						Receive: -1,
+// MarshalJSON encodes the HAR file, adding the custom fields to the encoding of its entries and their timings.
+func (h *harFile) MarshalJSON() ([]byte, error) {
+	data, err := h.HAR.MarshalJSON()
+	if err != nil || h.HAR == nil || h.HAR.Log == nil || (len(h.entryFields) == 0 && len(h.timingsFields) == 0) {
+		return data, err
+	}
+
+	for _, entry := range h.HAR.Log.Entries {
+		if entry == nil {
+			continue
+		}
+
+		entryData, err := entry.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		entryWithFields, err := h.marshalEntry(entry, entryData)
+		if err != nil {
+			return nil, err
+		}
+
+		data = bytes.Replace(data, entryData, entryWithFields, 1)
+	}
+
+	return data, nil
+}
+
+// marshalEntry adds the custom fields to the encoding of an entry, substituting the encoding of its timings with one that
+// includes the custom timings.
+func (h *harFile) marshalEntry(e *har.Entry, data []byte) ([]byte, error) {
+	if e.Timings != nil && len(h.timingsFields) > 0 {
+		timingsData, err := e.Timings.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		timings := newJSONObjectFrom(timingsData, nil)
+		timings.fields(h.timingsFields)
+
+		timingsWithFields, err := timings.close()
+		if err != nil {
+			return nil, err
+		}
+
+		key := []byte(`"timings":`)
+		data = bytes.Replace(data, append(key, timingsData...), append(key, timingsWithFields...), 1)
+	}
+
+	entry := newJSONObjectFrom(data, nil)
+	entry.fields(h.entryFields)
+
+	return entry.close()
+}
+
+// jsonObject writes the fields of a JSON object as they are encoded.
+type jsonObject struct {
+	buf   *bytes.Buffer
+	empty bool
+	err   error
+}
+
+// newJSONObjectFrom allows fields to be added to an already encoded JSON object.
+func newJSONObjectFrom(data []byte, err error) *jsonObject {
+	data = bytes.TrimSpace(data)
+	if err == nil && (len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}') {
+		err = errors.New("speakeasy-sdk: can't add fields to a value that isn't a JSON object")
+	}
+
+	if err != nil {
+		return &jsonObject{buf: &bytes.Buffer{}, err: err}
+	}
+
+	buf := bytes.NewBuffer(append([]byte{}, data[:len(data)-1]...))
+
+	return &jsonObject{buf: buf, empty: len(bytes.TrimSpace(data[1:len(data)-1])) == 0}
+}
+
+func (o *jsonObject) raw(key string, data []byte) {
+	if o.err != nil {
+		return
+	}
+
+	if !o.empty {
+		o.buf.WriteByte(',')
+	}
+	o.empty = false
+
+	keyData, _ := json.Marshal(key)
+	o.buf.Write(keyData)
+	o.buf.WriteByte(':')
+	o.buf.Write(data)
+}
+
+func (o *jsonObject) rawErr(key string, data []byte, err error) {
+	if err != nil {
+		if o.err == nil {
+			o.err = err
+		}
+
+		return
+	}
+
+	o.raw(key, data)
+}
+
+func (o *jsonObject) value(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	o.rawErr(key, data, err)
+}
+
+// fields writes custom fields in order of their keys so the encoding is deterministic.
+func (o *jsonObject) fields(fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		o.value(key, fields[key])
+	}
+}
+
+func (o *jsonObject) close() ([]byte, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	o.buf.WriteByte('}')
+
+	return o.buf.Bytes(), nil
+}
+
+func (h *harBuilder) buildHarFile(ctx context.Context, cw *captureWriter, r *http.Request, startTime time.Time, c *controller) *harFile {
+	resolvedURL := h.getResolvedURL(r, c)
+
+	return &harFile{
+		entryFields:   h.getEntryFields(ctx, cw, r, c),
+		timingsFields: h.getTimingsFields(cw, c),
+		HAR: &har.HAR{
+			Log: &har.Log{
+				Version: "1.2",
+				Creator: &har.Creator{
+					Name:    sdkName,
+					Version: speakeasyVersion,
+				},
+				Comment: "request capture for " + resolvedURL.String(),
+				Entries: []*har.Entry{
+					{
+						StartedDateTime: startTime.Format(time.RFC3339Nano),
+						Time:            float64(timeSince(startTime).Milliseconds()),
+						Request:         h.getHarRequest(ctx, cw, r, c, resolvedURL),
+						Response:        h.getHarResponse(ctx, cw, r, startTime, c),
+						Connection:      h.getConnection(r, c),
+						ServerIPAddress: h.getServerIPAddress(r, c),
+						Cache:           &har.Cache{},
+						Timings:         h.getHarTimings(cw, startTime),
 					},
 				},
 			},
@@ -49,13 +201,195 @@ func (h *harBuilder) buildHarFile(ctx context.Context, cw *captureWriter, r *htt
 	}
 }
 
-func getResolvedURL(r *http.Request, c *controller) *url.URL {
+// getHarTimings splits the time taken to handle the request into the time taken to read the request body (send),
+// the time until the first byte of the response was written (wait) and the time taken to write the response (receive).
+func (h *harBuilder) getHarTimings(cw *captureWriter, startTime time.Time) *har.Timings {
+	timings := cw.GetTimings()
+
+	sent := startTime
+	if timings.lastRequestRead.After(startTime) {
+		sent = timings.lastRequestRead
+	}
+
+	firstByte := timings.firstByte
+	if firstByte.IsZero() {
+		firstByte = timings.end
+	}
+	if firstByte.Before(sent) {
+		firstByte = sent
+	}
+
+	lastByte := timings.lastByte
+	if lastByte.Before(firstByte) {
+		lastByte = firstByte
+	}
+
+	return &har.Timings{
+		Send:    durationMillis(sent.Sub(startTime)),
+		Wait:    durationMillis(firstByte.Sub(sent)),
+		Receive: durationMillis(lastByte.Sub(firstByte)),
+	}
+}
+
+// getTimingsFields returns the custom timings of the entry, the Server-Timing metrics of the response and the time taken
+// by the upstream of a reverse proxy to respond.
+func (h *harBuilder) getTimingsFields(cw *captureWriter, c *controller) map[string]interface{} {
+	timings := h.getServerTimings(cw)
+
+	if c.upstream != nil {
+		timings["_upstream"] = durationMillis(c.upstream.latency)
+	}
+
+	return timings
+}
+
+// getServerTimings returns the metrics from the Server-Timing response headers as custom timings.
+func (h *harBuilder) getServerTimings(cw *captureWriter) map[string]interface{} {
+	timings := map[string]interface{}{}
+
+	for _, metric := range servertiming.Parse(cw.GetResponseHeader().Values("Server-Timing")) {
+		if metric.HasDuration {
+			timings["_"+metric.Name] = metric.Duration
+		}
+	}
+
+	return timings
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (h *harBuilder) getEntryFields(ctx context.Context, cw *captureWriter, r *http.Request, c *controller) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if c.requestID != "" {
+		fields["_requestId"] = c.requestID
+	}
+
+	if metadata := h.getMetadata(c); len(metadata) > 0 {
+		fields["_metadata"] = metadata
+	}
+
+	if c.outbound != nil {
+		fields["_outbound"] = true
+
+		if tls := getTLSInfo(c.outbound.tls); tls != nil {
+			fields["_tls"] = tls
+		}
+	} else {
+		if clientIP := h.getClientIP(r); clientIP != "" {
+			fields["_clientIPAddress"] = clientIP
+		}
+
+		if tls := getTLSInfo(r.TLS); tls != nil {
+			fields["_tls"] = tls
+		}
+	}
+
+	if c.skipBodies {
+		fields["_bodiesSkipped"] = true
+	}
+
+	if c.graphql != nil {
+		op := *c.graphql
+		variables, err := graphql.MaskVariableValues(op.Variables, c.graphqlVariableMasks)
+		if err == nil {
+			variables, err = maskGraphQLVariableFields(variables, c.requestFieldMasksString, c.requestFieldMasksNumber)
+		}
+		if err != nil {
+			log.From(ctx).Error("speakeasy-sdk: failed to mask graphql variables", zap.Error(err))
+			// the variables are dropped rather than risk capturing unmasked fields
+			variables = nil
+		}
+		op.Variables = variables
+
+		if c.skipBodies {
+			op.Variables = nil
+		}
+
+		fields["_graphql"] = op
+	}
+
+	if c.jsonrpc != nil {
+		fields["_jsonRpc"] = c.jsonrpc
+	}
+
+	if events := cw.GetServerSentEvents(); events != nil && !c.skipBodies {
+		for _, event := range events {
+			maskedData, err := bodymasking.MaskBodyRegex(event.Data, "application/json", c.responseFieldMasksString, c.responseFieldMasksNumber)
+			if err != nil {
+				log.From(ctx).Error("speakeasy-sdk: failed to mask server sent event", zap.Error(err))
+			} else {
+				event.Data = maskedData
+			}
+		}
+
+		fields["_serverSentEvents"] = events
+	}
+
+	if messages := cw.GetWebSocketMessages(); messages != nil && !c.skipBodies {
+		for _, message := range messages {
+			stringMasks, numberMasks := c.requestFieldMasksString, c.requestFieldMasksNumber
+			if message.Type == "receive" {
+				stringMasks, numberMasks = c.responseFieldMasksString, c.responseFieldMasksNumber
+			}
+
+			maskedData, err := bodymasking.MaskBodyRegex(message.Data, "application/json", stringMasks, numberMasks)
+			if err != nil {
+				log.From(ctx).Error("speakeasy-sdk: failed to mask websocket message", zap.Error(err))
+			} else {
+				message.Data = maskedData
+			}
+		}
+
+		fields["_webSocketMessages"] = messages
+	}
+
+	if cw.IsIncomplete() {
+		fields["_incomplete"] = true
+	}
+
+	if p := cw.GetPanic(); p != nil {
+		fields["_panic"] = p
+	}
+
+	if len(c.handlerErrors) > 0 {
+		fields["_errors"] = c.handlerErrors
+	}
+
+	return fields
+}
+
+// getMetadata merges the default tags with the metadata provided by the controller, which takes precedence.
+func (h *harBuilder) getMetadata(c *controller) map[string]string {
+	metadata := make(map[string]string, len(h.tags)+len(c.metadata))
+
+	for key, value := range h.tags {
+		metadata[key] = value
+	}
+
+	for key, value := range c.metadata {
+		metadata[key] = value
+	}
+
+	if c.upstream != nil {
+		metadata["upstreamHost"] = c.upstream.host
+	}
+
+	return metadata
+}
+
+func (h *harBuilder) getResolvedURL(r *http.Request, c *controller) *url.URL {
 	req := *r
 
-	// Taking advantage of Gorilla's ProxyHeaders parsing to resolve Forwarded headers
-	handlers.ProxyHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		req = *r
-	})).ServeHTTP(nil, &req)
+	// Forwarded headers can be spoofed by clients, so they are only honored from trusted proxies
+	if c.outbound == nil && h.isTrustedProxy(hostWithoutPort(r.RemoteAddr)) {
+		// Taking advantage of Gorilla's ProxyHeaders parsing to resolve Forwarded headers
+		handlers.ProxyHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req = *r
+		})).ServeHTTP(nil, &req)
+	}
 
 	url := req.URL
 
@@ -82,7 +416,8 @@ func getResolvedURL(r *http.Request, c *controller) *url.URL {
 		}
 	}
 
-	if url.Host == "" || url.Host != req.Host {
+	// Outbound requests may not set a Host, in which case the host of the URL is used
+	if req.Host != "" && url.Host != req.Host {
 		url.Host = req.Host
 	}
 
@@ -111,6 +446,9 @@ func (h *harBuilder) getHarRequest(ctx context.Context, cw *captureWriter, r *ht
 		return reqHeaders[i].Name < reqHeaders[j].Name
 	})
 
+	// Request trailers are only populated once the body has been read to EOF
+	reqHeaders = append(reqHeaders, getHarTrailers(r.Trailer, c.requestHeaderMasks)...)
+
 	reqQueryParams := []*har.NameValuePair{}
 
 	for key, values := range r.URL.Query() {
@@ -140,9 +478,10 @@ func (h *harBuilder) getHarRequest(ctx context.Context, cw *captureWriter, r *ht
 
 	postData := getPostData(r, cw, c, ctx)
 
+	// Use the number of bytes actually read as the Content-Length isn't known for chunked requests
 	var bodySize int64 = -1
-	if postData != nil {
-		bodySize = r.ContentLength
+	if r.Body != nil {
+		bodySize = int64(cw.GetRequestSize())
 	}
 
 	return &har.Request{
@@ -164,7 +503,11 @@ func (h *harBuilder) getHarResponse(ctx context.Context, cw *captureWriter, r *h
 
 	cookieParser := http.Response{Header: http.Header{}}
 
-	for key, values := range cw.origResW.Header() {
+	for key, values := range cw.GetResponseHeader() {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			continue
+		}
+
 		for _, value := range values {
 			if key == "Set-Cookie" {
 				cookieParser.Header.Add(key, value)
@@ -183,42 +526,32 @@ func (h *harBuilder) getHarResponse(ctx context.Context, cw *captureWriter, r *h
 		return resHeaders[i].Name < resHeaders[j].Name
 	})
 
+	resHeaders = append(resHeaders, getHarTrailers(cw.GetResponseTrailers(), c.responseHeaderMasks)...)
+
 	resCookies := getHarCookies(cookieParser.Cookies(), startTime, c.responseCookieMasks)
 
-	resContentType := cw.origResW.Header().Get("Content-Type")
+	resContentType := cw.GetResponseHeader().Get("Content-Type")
 	if resContentType == "" {
 		resContentType = "application/octet-stream" // default http content type
 	}
 
 	bodyText := ""
-	var bodySize int64 = -1
-	contentBodySize := -1
-	//nolint:nestif
-	if cw.GetStatus() == http.StatusNotModified {
+	// Use the number of bytes actually written as the Content-Length isn't known for chunked responses
+	bodySize := int64(cw.GetResponseSize())
+	switch {
+	case cw.GetStatus() == http.StatusNotModified:
 		bodySize = 0
-	} else {
-		if !cw.IsResValid() {
-			bodyText = "--dropped--"
-		} else {
-			bodyText = cw.GetResBuffer().String()
-			if cw.GetResBuffer().Len() > 0 {
-				contentBodySize = cw.GetResBuffer().Len()
-			}
-		}
-
-		contentLength := cw.origResW.Header().Get("Content-Length")
-		if contentLength != "" {
-			var err error
-			bodySize, err = strconv.ParseInt(contentLength, 10, 64)
-			if err != nil {
-				bodySize = -1
-			}
-		}
+	case c.skipBodies:
+		// the body size is still captured but not its contents
+	case !cw.IsResValid():
+		bodyText = "--dropped--"
+	default:
+		bodyText = cw.GetResBuffer().String()
 	}
 
 	b := bytes.NewBuffer([]byte{})
 	headerSize := -1
-	if err := cw.origResW.Header().Write(b); err != nil {
+	if err := cw.GetResponseHeader().Write(b); err != nil {
 		log.From(ctx).Error("speakeasy-sdk: failed to read length of response headers", zap.Error(err))
 	} else {
 		headerSize = b.Len()
@@ -233,24 +566,33 @@ func (h *harBuilder) getHarResponse(ctx context.Context, cw *captureWriter, r *h
 		}
 	}
 
+	statusText := http.StatusText(cw.status)
+	if c.statusText != "" {
+		statusText = c.statusText
+	}
+
 	return &har.Response{
 		Status:      int64(cw.status),
-		StatusText:  http.StatusText(cw.status),
+		StatusText:  statusText,
 		HTTPVersion: r.Proto,
 		Headers:     resHeaders,
 		Cookies:     resCookies,
 		Content: &har.Content{ // we are assuming we are getting the raw response here, so if we are put in the chain such that compression or encoding happens then the response text will be unreadable
-			Size:     int64(contentBodySize),
+			Size:     bodySize,
 			MimeType: resContentType,
 			Text:     bodyText,
 		},
-		RedirectURL: cw.origResW.Header().Get("Location"),
+		RedirectURL: cw.GetResponseHeader().Get("Location"),
 		HeadersSize: int64(headerSize),
 		BodySize:    bodySize,
 	}
 }
 
 func getPostData(r *http.Request, cw *captureWriter, c *controller, ctx context.Context) *har.PostData {
+	if c.skipBodies {
+		return nil
+	}
+
 	bodyText := "--dropped--"
 	if cw.IsReqValid() {
 		bodyText = cw.GetReqBuffer().String()
@@ -270,7 +612,16 @@ func getPostData(r *http.Request, cw *captureWriter, c *controller, ctx context.
 		}
 	}
 
-	maskedBody, err := bodymasking.MaskBodyRegex(bodyText, reqContentType, c.responseFieldMasksString, c.responseFieldMasksNumber)
+	if c.graphql != nil && len(c.graphqlVariableMasks) > 0 && cw.IsReqValid() {
+		maskedBody, err := graphql.MaskVariables([]byte(bodyText), c.graphqlVariableMasks)
+		if err != nil {
+			log.From(ctx).Error("speakeasy-sdk: failed to mask graphql variables", zap.Error(err))
+		} else {
+			bodyText = string(maskedBody)
+		}
+	}
+
+	maskedBody, err := bodymasking.MaskBodyRegex(bodyText, reqContentType, c.requestFieldMasksString, c.requestFieldMasksNumber)
 	if err != nil {
 		log.From(ctx).Error("speakeasy-sdk: failed to mask request body", zap.Error(err))
 	} else {
@@ -285,6 +636,27 @@ func getPostData(r *http.Request, cw *captureWriter, c *controller, ctx context.
 	return postData
 }
 
+func getHarTrailers(trailers http.Header, masks map[string]string) []*har.NameValuePair {
+	harTrailers := []*har.NameValuePair{}
+
+	for key, values := range trailers {
+		for _, value := range values {
+			mask, ok := masks[key]
+			if ok {
+				value = mask
+			}
+
+			harTrailers = append(harTrailers, &har.NameValuePair{Name: key, Value: value, Comment: "trailer"})
+		}
+	}
+
+	sort.SliceStable(harTrailers, func(i, j int) bool {
+		return harTrailers[i].Name < harTrailers[j].Name
+	})
+
+	return harTrailers
+}
+
 func getHarCookies(cookies []*http.Cookie, startTime time.Time, masks map[string]string) []*har.Cookie {
 	harCookies := []*har.Cookie{}
 	for _, cookie := range cookies {
@@ -0,0 +1,90 @@
+//nolint:testpackage
+package speakeasy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chromedp/cdproto/har"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarFile_MarshalJSON(t *testing.T) {
+	newHAR := func() *har.HAR {
+		return &har.HAR{
+			Log: &har.Log{
+				Version: "1.2",
+				Creator: &har.Creator{Name: sdkName, Version: speakeasyVersion},
+				Comment: "request capture for http://test.com/test",
+				Entries: []*har.Entry{
+					{
+						StartedDateTime: "2020-01-01T00:00:00Z",
+						Time:            1.5,
+						Request:         &har.Request{Method: "GET", URL: "http://test.com/test", HTTPVersion: "HTTP/1.1"},
+						Response:        &har.Response{Status: 200, StatusText: "OK", HTTPVersion: "HTTP/1.1", Content: &har.Content{}},
+						Cache:           &har.Cache{},
+						Timings:         &har.Timings{Send: 1, Wait: 0.5},
+						Connection:      "1234",
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		entryFields   map[string]interface{}
+		timingsFields map[string]interface{}
+		wantEntry     map[string]interface{}
+		wantTimings   map[string]interface{}
+	}{
+		{
+			name: "encodes HAR without custom fields",
+		},
+		{
+			name: "encodes custom fields of entry and timings",
+			entryFields: map[string]interface{}{
+				"_requestId": "abc",
+				"_metadata":  map[string]string{"env": "prod"},
+			},
+			timingsFields: map[string]interface{}{
+				"_upstream": 25.0,
+			},
+			wantEntry: map[string]interface{}{
+				"_requestId": "abc",
+				"_metadata":  map[string]interface{}{"env": "prod"},
+			},
+			wantTimings: map[string]interface{}{
+				"_upstream": 25.0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := (&harFile{HAR: newHAR(), entryFields: tt.entryFields, timingsFields: tt.timingsFields}).MarshalJSON()
+			require.NoError(t, err)
+
+			var got map[string]interface{}
+			require.NoError(t, json.Unmarshal(data, &got))
+
+			wantData, err := newHAR().MarshalJSON()
+			require.NoError(t, err)
+
+			var want map[string]interface{}
+			require.NoError(t, json.Unmarshal(wantData, &want))
+
+			entry := want["log"].(map[string]interface{})["entries"].([]interface{})[0].(map[string]interface{})
+			for key, value := range tt.wantEntry {
+				entry[key] = value
+			}
+
+			timings := entry["timings"].(map[string]interface{})
+			for key, value := range tt.wantTimings {
+				timings[key] = value
+			}
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package datadogtest tests capturing requests served by DataDog's traced ServeMux. It is a separate module so that
+// dd-trace-go isn't a dependency of the SDK.
+package datadogtest
@@ -0,0 +1,84 @@
+package datadogtest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testapi1"
+	testVersionID = "v1.0.0"
+)
+
+func TestMiddlewareWithMux_DataDogHttpTraceServerMux_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	type args struct {
+		path string
+		url  string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from DefaultServerMux",
+			args: args{
+				path: "/user",
+				url:  "http://test.com/user",
+			},
+			wantPathHint: "/user",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			r := httptrace.NewServeMux()
+
+			r.Handle(tt.args.path, sdkInstance.MiddlewareWithMux(r, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				handled = true
+			})))
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			assert.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
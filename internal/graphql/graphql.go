@@ -0,0 +1,237 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Request is a GraphQL request as sent in the body of a POST request https://graphql.org/learn/serving-over-http/
+type Request struct {
+	Query         string                     `json:"query"`
+	OperationName string                     `json:"operationName"`
+	Variables     map[string]json.RawMessage `json:"variables"`
+}
+
+// Operation is an operation defined in the document of a GraphQL request.
+type Operation struct {
+	// Type is the type of the operation, one of "query", "mutation" or "subscription".
+	Type string
+	// Name is the name of the operation, it is empty for anonymous operations.
+	Name string
+}
+
+// ParseRequest will parse the body of a GraphQL request, returning false if the body isn't a GraphQL request.
+func ParseRequest(body []byte) (*Request, bool) {
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" {
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// Operation returns the operation executed by the request, which is the operation named by OperationName
+// or the only operation in the document if no name is provided.
+func (r *Request) Operation() (Operation, bool) {
+	operations := ParseOperations(r.Query)
+
+	if r.OperationName != "" {
+		for _, op := range operations {
+			if op.Name == r.OperationName {
+				return op, true
+			}
+		}
+
+		return Operation{}, false
+	}
+
+	if len(operations) != 1 {
+		return Operation{}, false
+	}
+
+	return operations[0], true
+}
+
+// ParseOperations will return the operations defined in a GraphQL document, ignoring fragments and any
+// malformed definitions. Only the top level of the document is parsed.
+func ParseOperations(document string) []Operation {
+	operations := []Operation{}
+
+	tokens := tokenize(document)
+
+	depth := 0
+	definitionStart := true
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch token {
+		case "{":
+			// A selection set at the start of a definition is an anonymous query
+			if depth == 0 && definitionStart {
+				operations = append(operations, Operation{Type: "query"})
+			}
+
+			depth++
+			definitionStart = false
+
+			continue
+		case "}":
+			if depth > 0 {
+				depth--
+			}
+
+			if depth == 0 {
+				definitionStart = true
+			}
+
+			continue
+		}
+
+		if depth > 0 || !definitionStart {
+			continue
+		}
+
+		definitionStart = false
+
+		switch token {
+		case "query", "mutation", "subscription":
+			op := Operation{Type: token}
+
+			if i+1 < len(tokens) && isName(tokens[i+1]) {
+				op.Name = tokens[i+1]
+				i++
+			}
+
+			operations = append(operations, op)
+		}
+	}
+
+	return operations
+}
+
+// MaskVariables will replace the values of the named variables in the body of a GraphQL request with their masks.
+// The body is returned unchanged if it contains none of the variables.
+func MaskVariables(body []byte, masks map[string]string) ([]byte, error) {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	var variables map[string]json.RawMessage
+	if err := json.Unmarshal(req["variables"], &variables); err != nil || variables == nil {
+		return body, nil //nolint:nilerr
+	}
+
+	if !hasVariables(variables, masks) {
+		return body, nil
+	}
+
+	variables, err := MaskVariableValues(variables, masks)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	req["variables"] = data
+
+	return json.Marshal(req)
+}
+
+// MaskVariableValues will return the variables of a GraphQL request with the values of the named variables replaced by
+// their masks. The variables are returned unchanged if they contain none of the named variables.
+func MaskVariableValues(variables map[string]json.RawMessage, masks map[string]string) (map[string]json.RawMessage, error) {
+	if !hasVariables(variables, masks) {
+		return variables, nil
+	}
+
+	masked := make(map[string]json.RawMessage, len(variables))
+
+	for name, value := range variables {
+		if mask, ok := masks[name]; ok {
+			data, err := json.Marshal(mask)
+			if err != nil {
+				return nil, err
+			}
+
+			value = data
+		}
+
+		masked[name] = value
+	}
+
+	return masked, nil
+}
+
+func hasVariables(variables map[string]json.RawMessage, masks map[string]string) bool {
+	for name := range masks {
+		if _, ok := variables[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tokenize splits a GraphQL document into its names and punctuation, skipping whitespace, commas, comments and strings
+// as they can't affect the structure of the top level of the document.
+func tokenize(document string) []string {
+	tokens := []string{}
+
+	for i := 0; i < len(document); {
+		ch := document[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(document[i:], `"""`):
+			end := strings.Index(document[i+3:], `"""`)
+			if end < 0 {
+				return tokens
+			}
+
+			i += end + 6
+		case ch == '"':
+			i++
+			for i < len(document) && document[i] != '"' && document[i] != '\n' {
+				if document[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case isNameStart(ch):
+			start := i
+			for i < len(document) && isNameContinue(document[i]) {
+				i++
+			}
+
+			tokens = append(tokens, document[start:i])
+		default:
+			tokens = append(tokens, string(ch))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isName(token string) bool {
+	return token != "" && isNameStart(token[0])
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNameContinue(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,196 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOperations_Success(t *testing.T) {
+	type args struct {
+		document string
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantOperations []graphql.Operation
+	}{
+		{
+			name: "parses anonymous query shorthand",
+			args: args{
+				document: `{ users { name } }`,
+			},
+			wantOperations: []graphql.Operation{
+				{Type: "query"},
+			},
+		},
+		{
+			name: "parses named operations with variables",
+			args: args{
+				document: `query GetUser($id: ID! = "1", $filter: Filter = {active: true}) { user(id: $id) { name } }
+mutation UpdateUser($input: UserInput!) { updateUser(input: $input) { id } }
+subscription OnUser { user { id } }`,
+			},
+			wantOperations: []graphql.Operation{
+				{Type: "query", Name: "GetUser"},
+				{Type: "mutation", Name: "UpdateUser"},
+				{Type: "subscription", Name: "OnUser"},
+			},
+		},
+		{
+			name: "parses anonymous operations",
+			args: args{
+				document: `mutation { logout }`,
+			},
+			wantOperations: []graphql.Operation{
+				{Type: "mutation"},
+			},
+		},
+		{
+			name: "ignores fragments and nested keywords",
+			args: args{
+				document: `fragment UserFields on User { query mutation { id } }
+query GetUsers { users { ...UserFields } }`,
+			},
+			wantOperations: []graphql.Operation{
+				{Type: "query", Name: "GetUsers"},
+			},
+		},
+		{
+			name: "ignores comments and strings",
+			args: args{
+				document: `# query Commented { a }
+query Search { search(text: "mutation { }", description: """
+query Block {
+""") { id } }`,
+			},
+			wantOperations: []graphql.Operation{
+				{Type: "query", Name: "Search"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := graphql.ParseOperations(tt.args.document)
+			assert.Equal(t, tt.wantOperations, got)
+		})
+	}
+}
+
+func TestRequest_Operation_Success(t *testing.T) {
+	type args struct {
+		body string
+	}
+	tests := []struct {
+		name          string
+		args          args
+		wantOperation graphql.Operation
+		wantOk        bool
+	}{
+		{
+			name: "returns the only operation",
+			args: args{
+				body: `{"query": "query GetUser { user { id } }"}`,
+			},
+			wantOperation: graphql.Operation{Type: "query", Name: "GetUser"},
+			wantOk:        true,
+		},
+		{
+			name: "returns the named operation",
+			args: args{
+				body: `{"query": "query A { a } query B { b }", "operationName": "B"}`,
+			},
+			wantOperation: graphql.Operation{Type: "query", Name: "B"},
+			wantOk:        true,
+		},
+		{
+			name: "fails if the named operation isn't found",
+			args: args{
+				body: `{"query": "query A { a }", "operationName": "B"}`,
+			},
+		},
+		{
+			name: "fails if the operation is ambiguous",
+			args: args{
+				body: `{"query": "query A { a } query B { b }"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, ok := graphql.ParseRequest([]byte(tt.args.body))
+			require.True(t, ok)
+
+			op, ok := req.Operation()
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantOperation, op)
+		})
+	}
+}
+
+func TestParseRequest_Error(t *testing.T) {
+	for _, body := range []string{``, `[{"query": "{ a }"}]`, `{"data": {}}`} {
+		_, ok := graphql.ParseRequest([]byte(body))
+		assert.False(t, ok, body)
+	}
+}
+
+func TestMaskVariables_Success(t *testing.T) {
+	type args struct {
+		body  string
+		masks map[string]string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantBody string
+	}{
+		{
+			name: "masks variables of any type",
+			args: args{
+				body:  `{"query": "{ a }", "variables": {"password": "secret", "card": {"number": 4242}, "id": 1}}`,
+				masks: map[string]string{"password": "__masked__", "card": "__card__"},
+			},
+			wantBody: `{"query":"{ a }","variables":{"card":"__card__","id":1,"password":"__masked__"}}`,
+		},
+		{
+			name: "leaves body unchanged without matching variables",
+			args: args{
+				body:  `{"query": "{ a }", "variables": {"id": 1}}`,
+				masks: map[string]string{"password": "__masked__"},
+			},
+			wantBody: `{"query": "{ a }", "variables": {"id": 1}}`,
+		},
+		{
+			name: "leaves body unchanged without variables",
+			args: args{
+				body:  `{"query": "{ a }"}`,
+				masks: map[string]string{"password": "__masked__"},
+			},
+			wantBody: `{"query": "{ a }"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := graphql.MaskVariables([]byte(tt.args.body), tt.args.masks)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(got))
+		})
+	}
+}
+
+func TestMaskVariableValues_Success(t *testing.T) {
+	variables := map[string]json.RawMessage{"password": json.RawMessage(`"secret"`), "id": json.RawMessage(`1`)}
+
+	got, err := graphql.MaskVariableValues(variables, map[string]string{"password": "__masked__"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"password": json.RawMessage(`"__masked__"`), "id": json.RawMessage(`1`)}, got)
+	assert.Equal(t, json.RawMessage(`"secret"`), variables["password"])
+
+	got, err = graphql.MaskVariableValues(variables, map[string]string{"card": "__card__"})
+	require.NoError(t, err)
+	assert.Equal(t, variables, got)
+}
@@ -0,0 +1,17 @@
+// Package hooks holds the values of the SDK that are overridden by its tests and the tests of its framework integrations.
+package hooks
+
+import "time"
+
+// MaxCaptureSize is the maximum size in bytes of the request and response bodies captured.
+var MaxCaptureSize = 1 * 1024 * 1024
+
+// TimeNow returns the current time.
+var TimeNow = func() time.Time {
+	return time.Now()
+}
+
+// TimeSince returns the time elapsed since t.
+var TimeSince = func(t time.Time) time.Duration {
+	return time.Since(t)
+}
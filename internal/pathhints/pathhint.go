@@ -10,6 +10,11 @@ import (
 
 var varMatcher = regexp.MustCompile(`({(.*?:*.*?)}|:(.+?)\/|:(.*)|\*(.+)|\*)`)
 
+// maxSegmentLength is the maximum length of a segment taken from a request body, such as a GraphQL operation name.
+const maxSegmentLength = 64
+
+var segmentMatcher = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
 // NormalizePathHint will take a path hint from the various support routers/frameworks and normalize it to the OpenAPI spec.
 func NormalizePathHint(pathHint string) string {
 	matched := false
@@ -20,8 +25,12 @@ func NormalizePathHint(pathHint string) string {
 		switch {
 		case matches[0] == "*":
 			varMatch = "wildcard"
+		case matches[2] == "$":
+			// net/http ServeMux patterns use {$} to match only the path ending with a trailing slash
+			return ""
 		case matches[2] != "":
-			varMatch = strings.Split(matches[2], ":")[0]
+			// net/http ServeMux patterns use {name...} to match the remainder of the path
+			varMatch = strings.TrimSuffix(strings.Split(matches[2], ":")[0], "...")
 		case matches[3] != "":
 			return fmt.Sprintf("{%s}/", matches[3])
 		case matches[4] != "":
@@ -38,3 +47,28 @@ func NormalizePathHint(pathHint string) string {
 
 	return out
 }
+
+// ServeMuxPatternPath will return the path of a net/http ServeMux pattern, removing the method and host patterns can be
+// prefixed with since Go 1.22, for example "GET example.com/items/{id}" becomes "/items/{id}".
+func ServeMuxPatternPath(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+
+	// The method is separated from the rest of the pattern by whitespace
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+
+	// The host is everything before the first slash
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	return pattern
+}
+
+// IsSafeSegment will return whether a value taken from a request body, such as a GraphQL operation name or a JSON-RPC method,
+// can be used as a segment of a path hint. Values are limited to letters, digits, "_", "." and "-" and to 64 characters, so clients
+// can't create arbitrary path hints or path hints containing variables.
+func IsSafeSegment(segment string) bool {
+	return len(segment) <= maxSegmentLength && segmentMatcher.MatchString(segment)
+}
@@ -1,6 +1,7 @@
 package pathhints_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
@@ -51,6 +52,20 @@ func TestNormalizePathHint_Success(t *testing.T) {
 			},
 			wantNormalized: "/user/{id}/account/{action}",
 		},
+		{
+			name: "normalizes ServeMux remainder wildcard path hint",
+			args: args{
+				pathHint: "/files/{id}/{path...}",
+			},
+			wantNormalized: "/files/{id}/{path}",
+		},
+		{
+			name: "normalizes ServeMux end of path path hint",
+			args: args{
+				pathHint: "/files/{$}",
+			},
+			wantNormalized: "/files/",
+		},
 		{
 			name: "doesn't normalize an unknown format",
 			args: args{
@@ -66,3 +81,117 @@ func TestNormalizePathHint_Success(t *testing.T) {
 		})
 	}
 }
+
+func TestServeMuxPatternPath_Success(t *testing.T) {
+	type args struct {
+		pattern string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantPath string
+	}{
+		{
+			name: "returns path only pattern",
+			args: args{
+				pattern: "/items/{id}",
+			},
+			wantPath: "/items/{id}",
+		},
+		{
+			name: "removes method",
+			args: args{
+				pattern: "GET /items/{id}",
+			},
+			wantPath: "/items/{id}",
+		},
+		{
+			name: "removes method separated by multiple spaces and tabs",
+			args: args{
+				pattern: "POST \t /items",
+			},
+			wantPath: "/items",
+		},
+		{
+			name: "removes host",
+			args: args{
+				pattern: "example.com/items/{id}",
+			},
+			wantPath: "/items/{id}",
+		},
+		{
+			name: "removes method and host",
+			args: args{
+				pattern: "DELETE example.com/items/{id}",
+			},
+			wantPath: "/items/{id}",
+		},
+		{
+			name: "returns empty pattern",
+			args: args{
+				pattern: "",
+			},
+			wantPath: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := pathhints.ServeMuxPatternPath(tt.args.pattern)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
+
+func TestIsSafeSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		segment  string
+		wantSafe bool
+	}{
+		{
+			name:     "allows name",
+			segment:  "GetUser",
+			wantSafe: true,
+		},
+		{
+			name:     "allows dotted method",
+			segment:  "rpc.user_get-v2",
+			wantSafe: true,
+		},
+		{
+			name:     "allows segment of max length",
+			segment:  strings.Repeat("a", 64),
+			wantSafe: true,
+		},
+		{
+			name:     "rejects empty segment",
+			segment:  "",
+			wantSafe: false,
+		},
+		{
+			name:     "rejects segment over max length",
+			segment:  strings.Repeat("a", 65),
+			wantSafe: false,
+		},
+		{
+			name:     "rejects slashes",
+			segment:  "users/{id}",
+			wantSafe: false,
+		},
+		{
+			name:     "rejects variables",
+			segment:  ":id",
+			wantSafe: false,
+		},
+		{
+			name:     "rejects whitespace",
+			segment:  "get user",
+			wantSafe: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantSafe, pathhints.IsSafeSegment(tt.segment))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package servertiming
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Metric is a single metric from a Server-Timing header https://www.w3.org/TR/server-timing/
+type Metric struct {
+	Name        string
+	Duration    float64
+	HasDuration bool
+	Description string
+}
+
+// Parse will parse the values of Server-Timing headers into their metrics, ignoring any malformed metrics.
+func Parse(values []string) []Metric {
+	metrics := []Metric{}
+
+	for _, value := range values {
+		for _, entry := range split(value, ',') {
+			params := split(entry, ';')
+
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+
+			metric := Metric{Name: name}
+
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				key = strings.ToLower(strings.TrimSpace(key))
+				val = unquote(strings.TrimSpace(val))
+
+				switch key {
+				case "dur":
+					if metric.HasDuration {
+						continue
+					}
+
+					dur, err := strconv.ParseFloat(val, 64)
+					if err == nil {
+						metric.Duration = dur
+						metric.HasDuration = true
+					}
+				case "desc":
+					if metric.Description == "" {
+						metric.Description = val
+					}
+				}
+			}
+
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return metrics
+}
+
+// split splits s by sep, ignoring any separators within quoted strings.
+func split(s string, sep rune) []string {
+	parts := []string{}
+
+	inQuotes := false
+	escaped := false
+	start := 0
+
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var b strings.Builder
+
+	escaped := false
+	for _, c := range s[1 : len(s)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+
+		escaped = false
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
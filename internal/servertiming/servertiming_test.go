@@ -0,0 +1,74 @@
+package servertiming_test
+
+import (
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/servertiming"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Success(t *testing.T) {
+	type args struct {
+		values []string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantMetrics []servertiming.Metric
+	}{
+		{
+			name: "parses single metric with duration",
+			args: args{
+				values: []string{"db;dur=53"},
+			},
+			wantMetrics: []servertiming.Metric{
+				{Name: "db", Duration: 53, HasDuration: true},
+			},
+		},
+		{
+			name: "parses multiple metrics across headers",
+			args: args{
+				values: []string{"db;dur=53, app;dur=47.2", "cache;desc=\"Cache Read\";dur=23.2"},
+			},
+			wantMetrics: []servertiming.Metric{
+				{Name: "db", Duration: 53, HasDuration: true},
+				{Name: "app", Duration: 47.2, HasDuration: true},
+				{Name: "cache", Duration: 23.2, HasDuration: true, Description: "Cache Read"},
+			},
+		},
+		{
+			name: "parses metrics without durations",
+			args: args{
+				values: []string{"miss, dc;desc=atl"},
+			},
+			wantMetrics: []servertiming.Metric{
+				{Name: "miss"},
+				{Name: "dc", Description: "atl"},
+			},
+		},
+		{
+			name: "ignores separators in quoted descriptions",
+			args: args{
+				values: []string{`total;desc="a, \"quoted\"; value";dur=1.5`},
+			},
+			wantMetrics: []servertiming.Metric{
+				{Name: "total", Duration: 1.5, HasDuration: true, Description: `a, "quoted"; value`},
+			},
+		},
+		{
+			name: "uses first duration and ignores malformed values",
+			args: args{
+				values: []string{"db;dur=abc, app;dur=1;dur=2, ;dur=3"},
+			},
+			wantMetrics: []servertiming.Metric{
+				{Name: "db"},
+				{Name: "app", Duration: 1, HasDuration: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantMetrics, servertiming.Parse(tt.args.values))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package speakeasy
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
+)
+
+// JSONRPCConfig configures the capture of JSON-RPC 2.0 requests, which are given path hints for the method called
+// rather than the single path all calls are served on.
+type JSONRPCConfig struct {
+	// Paths are the paths JSON-RPC requests are served on, if not provided requests to any path with a JSON-RPC 2.0 body are matched.
+	Paths []string
+}
+
+// jsonrpcCall records the methods called by a JSON-RPC request.
+type jsonrpcCall struct {
+	Methods []string `json:"methods"`
+	Batch   bool     `json:"batch,omitempty"`
+}
+
+type jsonrpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+}
+
+// resolveJSONRPCCall parses the JSON-RPC methods called from a captured request body, returning nil if the request
+// isn't a JSON-RPC 2.0 request made to one of the configured paths.
+func (s *Speakeasy) resolveJSONRPCCall(r *http.Request, cw *captureWriter) *jsonrpcCall {
+	cfg := s.config.JSONRPC
+	if cfg == nil || r.Method != http.MethodPost || !cw.IsReqValid() {
+		return nil
+	}
+
+	if len(cfg.Paths) > 0 {
+		isJSONRPCPath := false
+
+		for _, p := range cfg.Paths {
+			if strings.TrimSuffix(r.URL.Path, "/") == strings.TrimSuffix(p, "/") {
+				isJSONRPCPath = true
+				break
+			}
+		}
+
+		if !isJSONRPCPath {
+			return nil
+		}
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+			return nil
+		}
+	}
+
+	body := bytes.TrimSpace(cw.GetReqBuffer().Bytes())
+
+	// Batches are sent as an array of calls
+	if bytes.HasPrefix(body, []byte("[")) {
+		var reqs []jsonrpcRequest
+		if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+			return nil
+		}
+
+		call := &jsonrpcCall{Batch: true}
+
+		for _, req := range reqs {
+			if !req.isValid() {
+				return nil
+			}
+
+			call.Methods = append(call.Methods, req.Method)
+		}
+
+		return call
+	}
+
+	var req jsonrpcRequest
+	if err := json.Unmarshal(body, &req); err != nil || !req.isValid() {
+		return nil
+	}
+
+	return &jsonrpcCall{Methods: []string{req.Method}}
+}
+
+func (r jsonrpcRequest) isValid() bool {
+	return r.JSONRPC == "2.0" && r.Method != ""
+}
+
+// getJSONRPCPathHint appends the method called to the path hint of a JSON-RPC request, for example "/rpc/getUser".
+// Batches are given the path hint "/rpc/batch" with the methods they call recorded in the _jsonRpc field of the HAR entry.
+// The path of the request is used if there is no path hint from a router.
+func getJSONRPCPathHint(pathHint string, r *http.Request, call *jsonrpcCall) string {
+	if pathHint == "" {
+		pathHint = r.URL.Path
+	}
+
+	method := "batch"
+	if !call.Batch {
+		method = call.Methods[0]
+	}
+
+	// The method is chosen by the client, so methods that can't be used in a path hint aren't appended
+	if !pathhints.IsSafeSegment(method) {
+		return pathHint
+	}
+
+	return strings.TrimSuffix(pathHint, "/") + "/" + method
+}
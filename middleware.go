@@ -3,22 +3,25 @@ package speakeasy
 import (
 	"net/http"
 
-	"github.com/gin-gonic/gin"
-	"github.com/go-chi/chi/v5"
-	"github.com/gorilla/mux"
-	"github.com/labstack/echo/v4"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
 )
 
 // Middleware setups up the default SDK instance to start capturing requests from routers that support http.Handlers.
-// Currently only gorilla/mux, go-chi/chi routers and the http.DefaultServerMux are supported for automatically
-// capturing path hints. Otherwise path hints can be supplied by a handler through the speakeasy MiddlewareController.
+// Path hints are captured automatically from the http.DefaultServerMux and (from Go 1.23) the http.ServeMux serving the request.
+// Path hints for other routers can be resolved by providing a PathHintResolver in the config, such as the resolvers of the
+// speakeasymux, speakeasychi, speakeasyhttprouter and speakeasybunrouter modules, otherwise path hints can be supplied by a
+// handler through the speakeasy MiddlewareController. The path hints of gorilla/mux and go-chi/chi routers are also still
+// resolved without their resolvers, but this is deprecated and will be removed in the next major version of the SDK.
 func Middleware(next http.Handler) http.Handler {
 	return defaultInstance.Middleware(next)
 }
 
 // Middleware setups the current instance of the SDK to start capturing requests from routers that support http.Handlers.
-// Currently only gorilla/mux, go-chi/chi routers and the http.DefaultServerMux are supported for automatically
-// capturing path hints. Otherwise path hints can be supplied by a handler through the speakeasy MiddlewareController.
+// Path hints are captured automatically from the http.DefaultServerMux and (from Go 1.23) the http.ServeMux serving the request.
+// Path hints for other routers can be resolved by providing a PathHintResolver in the config, such as the resolvers of the
+// speakeasymux, speakeasychi, speakeasyhttprouter and speakeasybunrouter modules, otherwise path hints can be supplied by a
+// handler through the speakeasy MiddlewareController. The path hints of gorilla/mux and go-chi/chi routers are also still
+// resolved without their resolvers, but this is deprecated and will be removed in the next major version of the SDK.
 //
 //nolint:nolintlint,contextcheck
 func (s *Speakeasy) Middleware(next http.Handler) http.Handler {
@@ -30,28 +33,25 @@ func (s *Speakeasy) Middleware(next http.Handler) http.Handler {
 				return pathHint
 			}
 
-			// First check gorilla/mux for a path hint
-			route := mux.CurrentRoute(r)
-			if route != nil {
-				pathHint, _ := route.GetPathTemplate()
-				if pathHint != "" {
-					return pathHint
-				}
+			// Then check any resolvers provided in the config
+			if pathHint := resolvePathHint(r, s.config.PathHintResolvers); pathHint != "" {
+				return pathHint
 			}
 
-			// Check chi router for a path hint
-			routeContext := chi.RouteContext(r.Context())
-			if routeContext != nil {
-				pathHint := routeContext.RoutePattern()
-				if pathHint != "" {
-					return pathHint
-				}
+			// Check gorilla/mux and chi routers for a path hint, until they are only resolved by their modules
+			if pathHint := resolveRouterPathHint(r); pathHint != "" {
+				return pathHint
+			}
+
+			// Check for the pattern of a net/http ServeMux the request was served by
+			if pattern := requestPattern(r); pattern != "" {
+				return pathhints.ServeMuxPatternPath(pattern)
 			}
 
 			// lastly check the default server mux for a path hint
 			_, pathHint = http.DefaultServeMux.Handler(r)
 
-			return pathHint
+			return pathhints.ServeMuxPatternPath(pathHint)
 		})
 	})
 }
@@ -77,76 +77,13 @@ func (s *Speakeasy) MiddlewareWithMux(mux Mux, next http.Handler) http.Handler {
 				return pathHint
 			}
 
-			_, pathHint = mux.Handler(r)
-
-			return pathHint
-		})
-	})
-}
-
-// GinMiddleware setups up the default SDK instance to start capturing requests from the gin http framework.
-func GinMiddleware(c *gin.Context) {
-	defaultInstance.GinMiddleware(c)
-}
-
-// GinMiddleware setups the current instance of the SDK to start capturing requests from the gin http framework.
-func (s *Speakeasy) GinMiddleware(c *gin.Context) {
-	s.handleRequestResponse(c.Writer, c.Request, func(w http.ResponseWriter, r *http.Request) {
-		c.Writer = &ginResponseWriter{c.Writer, w}
-		c.Request = r
-
-		c.Next()
-	}, func(c *gin.Context) func(r *http.Request) string {
-		return func(r *http.Request) string {
-			pathHint := s.MatchOpenAPIPath(r)
-			if pathHint != "" {
+			if pathHint := resolvePathHint(r, s.config.PathHintResolvers); pathHint != "" {
 				return pathHint
 			}
 
-			return c.FullPath()
-		}
-	}(c))
-}
-
-// EchoMiddleware setups up the default SDK instance to start capturing requests from the echo http framework.
-func EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return defaultInstance.EchoMiddleware(next)
-}
-
-// EchoMiddleware setups the current instance of the SDK to start capturing requests from the echo http framework.
-func (s *Speakeasy) EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		return s.handleRequestResponseError(c.Response(), c.Request(), func(w http.ResponseWriter, r *http.Request) error {
-			c.SetResponse(echo.NewResponse(w, c.Echo()))
-			c.SetRequest(r)
-
-			return next(c)
-		}, func(r *http.Request) string {
-			pathHint := s.MatchOpenAPIPath(r)
-			if pathHint != "" {
-				return pathHint
-			}
+			_, pathHint = mux.Handler(r)
 
-			return c.Path()
+			return pathhints.ServeMuxPatternPath(pathHint)
 		})
-	}
-}
-
-type ginResponseWriter struct {
-	gin.ResponseWriter
-	writer http.ResponseWriter
-}
-
-var _ gin.ResponseWriter = &ginResponseWriter{}
-
-func (g *ginResponseWriter) Write(data []byte) (int, error) {
-	return g.writer.Write(data)
-}
-
-func (g *ginResponseWriter) WriteString(s string) (int, error) {
-	return g.writer.Write([]byte(s))
-}
-
-func (g *ginResponseWriter) WriteHeader(statusCode int) {
-	g.writer.WriteHeader(statusCode)
+	})
 }
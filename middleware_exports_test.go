@@ -1,19 +1,27 @@
 package speakeasy
 
-import "time"
+import (
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/hooks"
+)
 
 func ExportSetTimeNow(t time.Time) {
-	timeNow = func() time.Time {
+	hooks.TimeNow = func() time.Time {
 		return t
 	}
 }
 
+func ExportSetTimeNowFunc(f func() time.Time) {
+	hooks.TimeNow = f
+}
+
 func ExportSetTimeSince(d time.Duration) {
-	timeSince = func(t time.Time) time.Duration {
+	hooks.TimeSince = func(t time.Time) time.Duration {
 		return d
 	}
 }
 
 func ExportSetMaxCaptureSize(ms int) {
-	maxCaptureSize = ms
+	hooks.MaxCaptureSize = ms
 }
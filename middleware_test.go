@@ -1,7 +1,6 @@
 package speakeasy_test
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -19,17 +18,14 @@ import (
 
 	"github.com/chromedp/cdproto/har"
 	"github.com/gin-gonic/gin"
-	"github.com/go-chi/chi/v5"
-	"github.com/gorilla/mux"
-	"github.com/labstack/echo/v4"
 	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
 	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
-	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 )
 
 func TestMain(m *testing.M) {
@@ -41,100 +37,21 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-type header struct {
-	Key    string   `json:"key"`
-	Values []string `json:"values"`
-}
-type fields struct {
-	MaxCaptureSize int `json:"max_capture_size,omitempty"`
-}
-type args struct {
-	Method                   string            `json:"method"`
-	URL                      string            `json:"url"`
-	Headers                  []header          `json:"headers"`
-	Body                     string            `json:"body"`
-	RequestStartTime         time.Time         `json:"request_start_time"`
-	ElapsedTime              int               `json:"elapsed_time"`
-	ResponseStatus           int               `json:"response_status"`
-	ResponseBody             string            `json:"response_body"`
-	ResponseHeaders          []header          `json:"response_headers"`
-	QueryStringMasks         map[string]string `json:"query_string_masks"`
-	RequestHeaderMasks       map[string]string `json:"request_header_masks"`
-	RequestCookieMasks       map[string]string `json:"request_cookie_masks"`
-	RequestFieldMasksString  map[string]string `json:"request_field_masks_string"`
-	RequestFieldMasksNumber  map[string]string `json:"request_field_masks_number"`
-	ResponseHeaderMasks      map[string]string `json:"response_header_masks"`
-	ResponseCookieMasks      map[string]string `json:"response_cookie_masks"`
-	ResponseFieldMasksString map[string]string `json:"response_field_masks_string"`
-	ResponseFieldMasksNumber map[string]string `json:"response_field_masks_number"`
-}
-type test struct {
-	Name    string `json:"name"`
-	Fields  fields `json:"fields"`
-	Args    args   `json:"args"`
-	WantHAR string `json:"want_har"`
-}
-
 const (
 	testAPIKey    = "test"
 	testApiID     = "testapi1"
 	testVersionID = "v1.0.0"
 )
 
-func loadTestData(t *testing.T) []test {
-	t.Helper()
-
-	files, err := os.ReadDir("testdata")
-	require.NoError(t, err)
-
-	tests := []test{}
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), "_input.json") {
-			baseName := strings.TrimSuffix(file.Name(), "_input.json")
-
-			inputData, err := os.ReadFile("testdata/" + file.Name())
-			require.NoError(t, err)
-
-			tt := test{}
-			err = json.Unmarshal(inputData, &tt)
-			require.NoError(t, err)
-
-			outputData, err := os.ReadFile("testdata/" + baseName + "_output.json")
-			require.NoError(t, err)
-
-			outputDataMinified := bytes.NewBuffer([]byte{})
-			err = json.Compact(outputDataMinified, outputData)
-			require.NoError(t, err)
-
-			tt.WantHAR = outputDataMinified.String()
-
-			tests = append(tests, tt)
-		}
-	}
-
-	return tests
-}
-
 func TestSpeakeasy_Middleware_Capture_Success(t *testing.T) {
-	tests := loadTestData(t)
+	tests := speakeasytest.LoadGoldenTests(t, "testdata")
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
-			speakeasy.ExportSetMaxCaptureSize(tt.Fields.MaxCaptureSize)
+			tt.Setup(t)
 
 			captured := false
 			handled := false
 
-			if tt.Args.RequestStartTime.IsZero() {
-				speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			} else {
-				speakeasy.ExportSetTimeNow(tt.Args.RequestStartTime)
-			}
-			if tt.Args.ElapsedTime == 0 {
-				speakeasy.ExportSetTimeSince(1 * time.Millisecond)
-			} else {
-				speakeasy.ExportSetTimeSince(time.Duration(tt.Args.ElapsedTime) * time.Millisecond)
-			}
-
 			wg := &sync.WaitGroup{}
 			wg.Add(1)
 
@@ -160,60 +77,7 @@ func TestSpeakeasy_Middleware_Capture_Success(t *testing.T) {
 
 			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				ctrl, _ := speakeasy.MiddlewareController(req)
-
-				if tt.Args.QueryStringMasks != nil {
-					for k, v := range tt.Args.QueryStringMasks {
-						ctrl.Masking(speakeasy.WithQueryStringMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.RequestHeaderMasks != nil {
-					for k, v := range tt.Args.RequestHeaderMasks {
-						ctrl.Masking(speakeasy.WithRequestHeaderMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.RequestCookieMasks != nil {
-					for k, v := range tt.Args.RequestCookieMasks {
-						ctrl.Masking(speakeasy.WithRequestCookieMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.RequestFieldMasksString != nil {
-					for k, v := range tt.Args.RequestFieldMasksString {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskString([]string{k}, v))
-					}
-				}
-
-				if tt.Args.RequestFieldMasksNumber != nil {
-					for k, v := range tt.Args.RequestFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskNumber([]string{k}, v))
-					}
-				}
-
-				if tt.Args.ResponseHeaderMasks != nil {
-					for k, v := range tt.Args.ResponseHeaderMasks {
-						ctrl.Masking(speakeasy.WithResponseHeaderMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.ResponseCookieMasks != nil {
-					for k, v := range tt.Args.ResponseCookieMasks {
-						ctrl.Masking(speakeasy.WithResponseCookieMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.ResponseFieldMasksString != nil {
-					for k, v := range tt.Args.ResponseFieldMasksString {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskString([]string{k}, v))
-					}
-				}
-
-				if tt.Args.ResponseFieldMasksNumber != nil {
-					for k, v := range tt.Args.ResponseFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskNumber([]string{k}, v))
-					}
-				}
+				ctrl.Masking(tt.MaskingOptions()...)
 
 				for _, header := range tt.Args.ResponseHeaders {
 					for _, val := range header.Values {
@@ -240,49 +104,31 @@ func TestSpeakeasy_Middleware_Capture_Success(t *testing.T) {
 
 			w := httptest.NewRecorder()
 
-			var req *http.Request
-			var err error
-			if tt.Args.Body == "" {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
-			} else {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, bytes.NewBuffer([]byte(tt.Args.Body)))
-			}
-			assert.NoError(t, err)
-
-			for _, header := range tt.Args.Headers {
-				for _, val := range header.Values {
-					req.Header.Add(header.Key, val)
-				}
-			}
-
-			h.ServeHTTP(w, req)
+			h.ServeHTTP(w, tt.NewRequest(t))
 
 			wg.Wait()
 
 			assert.True(t, handled, "middleware did not call handler")
 			assert.True(t, captured, "middleware did not capture request")
-
-			responseStatus := http.StatusOK
-			if tt.Args.ResponseStatus > 0 {
-				responseStatus = tt.Args.ResponseStatus
-			}
-
-			assert.Equal(t, responseStatus, w.Code)
+			assert.Equal(t, tt.WantResponseStatus(), w.Code)
 		})
 	}
 }
 
 func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 	type args struct {
-		url     string
-		headers map[string]string
-		host    string
-		https   bool
+		url        string
+		headers    map[string]string
+		host       string
+		https      bool
+		remoteAddr string
 	}
 	tests := []struct {
 		name            string
+		trustedProxies  []string
 		args            args
 		wantResolvedURL string
+		wantClientIP    string
 	}{
 		{
 			name: "successfully resolves relative URL",
@@ -309,27 +155,108 @@ func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 			wantResolvedURL: "https://localhost:8080/v1/users",
 		},
 		{
-			name: "successfully resolves relative URL behind proxy",
+			name:           "successfully resolves relative URL behind proxy",
+			trustedProxies: []string{"0.0.0.0/0", "::/0"},
 			args: args{
-				url:  "/v1/users",
-				host: "localhost:8080",
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "10.0.0.2:54321",
+				headers: map[string]string{
+					"X-Forwarded-Host":  "dev.speakeasyapi.dev",
+					"X-Forwarded-Proto": "https",
+				},
+			},
+			wantResolvedURL: "https://dev.speakeasyapi.dev/v1/users",
+			wantClientIP:    "10.0.0.2",
+		},
+		{
+			name:           "successfully resolves absolute URL behind proxy",
+			trustedProxies: []string{"0.0.0.0/0", "::/0"},
+			args: args{
+				url:        "http://10.0.0.1:8080/v1/users",
+				remoteAddr: "10.0.0.2:54321",
 				headers: map[string]string{
 					"X-Forwarded-Host":  "dev.speakeasyapi.dev",
 					"X-Forwarded-Proto": "https",
 				},
 			},
 			wantResolvedURL: "https://dev.speakeasyapi.dev/v1/users",
+			wantClientIP:    "10.0.0.2",
 		},
 		{
-			name: "successfully resolves absolute URL behind proxy",
+			name:           "successfully resolves URL behind trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
 			args: args{
-				url: "http://10.0.0.1:8080/v1/users",
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "10.0.0.2:54321",
 				headers: map[string]string{
 					"X-Forwarded-Host":  "dev.speakeasyapi.dev",
 					"X-Forwarded-Proto": "https",
+					"X-Forwarded-For":   "203.0.113.7, 10.0.0.3",
 				},
 			},
 			wantResolvedURL: "https://dev.speakeasyapi.dev/v1/users",
+			wantClientIP:    "203.0.113.7",
+		},
+		{
+			name:           "ignores forwarded headers from untrusted client",
+			trustedProxies: []string{"10.0.0.0/8"},
+			args: args{
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "198.51.100.4:54321",
+				headers: map[string]string{
+					"X-Forwarded-Host":  "dev.speakeasyapi.dev",
+					"X-Forwarded-Proto": "https",
+					"X-Forwarded-For":   "203.0.113.7",
+				},
+			},
+			wantResolvedURL: "http://localhost:8080/v1/users",
+			wantClientIP:    "198.51.100.4",
+		},
+		{
+			name:           "ignores spoofed client IP added before trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			args: args{
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "10.0.0.2:54321",
+				headers: map[string]string{
+					"X-Forwarded-For": "1.1.1.1, 203.0.113.7",
+				},
+			},
+			wantResolvedURL: "http://localhost:8080/v1/users",
+			wantClientIP:    "203.0.113.7",
+		},
+		{
+			name: "ignores forwarded headers without trusted proxies",
+			args: args{
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "198.51.100.4:54321",
+				headers: map[string]string{
+					"X-Forwarded-Host":  "dev.speakeasyapi.dev",
+					"X-Forwarded-Proto": "https",
+					"X-Forwarded-For":   "203.0.113.7",
+				},
+			},
+			wantResolvedURL: "http://localhost:8080/v1/users",
+			wantClientIP:    "198.51.100.4",
+		},
+		{
+			name:           "ignores forwarded headers when no proxies are trusted",
+			trustedProxies: []string{},
+			args: args{
+				url:        "/v1/users",
+				host:       "localhost:8080",
+				remoteAddr: "10.0.0.2:54321",
+				headers: map[string]string{
+					"Forwarded": "for=203.0.113.7;host=dev.speakeasyapi.dev;proto=https",
+				},
+			},
+			wantResolvedURL: "http://localhost:8080/v1/users",
+			wantClientIP:    "10.0.0.2",
 		},
 	}
 	for _, tt := range tests {
@@ -344,9 +271,10 @@ func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 			wg.Add(1)
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
-				APIKey:    testAPIKey,
-				ApiID:     testApiID,
-				VersionID: testVersionID,
+				APIKey:         testAPIKey,
+				ApiID:          testApiID,
+				VersionID:      testVersionID,
+				TrustedProxies: tt.trustedProxies,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
 					var h har.HAR
 
@@ -354,18 +282,28 @@ func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 					require.NoError(t, err)
 
 					assert.Equal(t, tt.wantResolvedURL, h.Log.Entries[0].Request.URL)
+
+					var fields struct {
+						Log struct {
+							Entries []struct {
+								ClientIPAddress string `json:"_clientIPAddress"`
+							} `json:"entries"`
+						} `json:"log"`
+					}
+
+					err = json.Unmarshal([]byte(req.GetHar()), &fields)
+					require.NoError(t, err)
+
+					assert.Equal(t, tt.wantClientIP, fields.Log.Entries[0].ClientIPAddress)
 					captured = true
 					wg.Done()
 				}),
 			})
 
-			r := mux.NewRouter()
-			r.Use(sdkInstance.Middleware)
-
-			r.Methods(http.MethodGet).Path("/v1/users").HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				handled = true
-			})
+			}))
 
 			w := httptest.NewRecorder()
 
@@ -378,11 +316,14 @@ func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 			for k, v := range tt.args.headers {
 				req.Header.Add(k, v)
 			}
+			if tt.args.remoteAddr != "" {
+				req.RemoteAddr = tt.args.remoteAddr
+			}
 			if tt.args.https {
 				req.TLS = &tls.ConnectionState{}
 			}
 
-			r.ServeHTTP(w, req)
+			h.ServeHTTP(w, req)
 
 			wg.Wait()
 
@@ -393,11 +334,10 @@ func TestSpeakeasy_Middleware_URL_Resolve_Success(t *testing.T) {
 	}
 }
 
-func TestSpeakeasy_Middleware_GorillaMux_PathHint_Success(t *testing.T) {
+func TestSpeakeasy_Middleware_ServerMux_PathHint_Success(t *testing.T) {
 	type args struct {
-		path    string
-		url     string
-		devHint string
+		path string
+		url  string
 	}
 	tests := []struct {
 		name         string
@@ -405,29 +345,12 @@ func TestSpeakeasy_Middleware_GorillaMux_PathHint_Success(t *testing.T) {
 		wantPathHint string
 	}{
 		{
-			name: "captures simple path hint from mux",
-			args: args{
-				path: "/user/{id}",
-				url:  "http://test.com/user/1",
-			},
-			wantPathHint: "/user/{id}",
-		},
-		{
-			name: "captures more complex path hint from mux",
-			args: args{
-				path: "/user/{id:[0-9]+}/account/{accountID}",
-				url:  "http://test.com/user/1/account/abcdefg",
-			},
-			wantPathHint: "/user/{id}/account/{accountID}",
-		},
-		{
-			name: "path hint is overridden by dev hint",
+			name: "captures simple path hint from DefaultServerMux",
 			args: args{
-				path:    "/user/{id:[0-9]+}/account/{accountID}",
-				url:     "http://test.com/user/1/account/abcdefg",
-				devHint: "/user/{id}/account/{accountID}",
+				path: "/user",
+				url:  "http://test.com/user",
 			},
-			wantPathHint: "/user/{id}/account/{accountID}",
+			wantPathHint: "/user",
 		},
 	}
 	for _, tt := range tests {
@@ -452,19 +375,12 @@ func TestSpeakeasy_Middleware_GorillaMux_PathHint_Success(t *testing.T) {
 				}),
 			})
 
-			r := mux.NewRouter()
-			r.Use(sdkInstance.Middleware)
-
-			r.Methods(http.MethodGet).Path(tt.args.path).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-				if tt.args.devHint != "" {
-					ctrl, _ := speakeasy.MiddlewareController(req)
-					require.NotNil(t, ctrl)
-					ctrl.PathHint(tt.args.devHint)
-				}
+			r := http.DefaultServeMux
 
+			r.Handle(tt.args.path, sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				handled = true
-			})
+			})))
 
 			w := httptest.NewRecorder()
 
@@ -482,31 +398,44 @@ func TestSpeakeasy_Middleware_GorillaMux_PathHint_Success(t *testing.T) {
 	}
 }
 
-func TestSpeakeasy_Middleware_Chi_PathHint_Success(t *testing.T) {
+func TestSpeakeasy_Middleware_Capture_CustomerID_Success(t *testing.T) {
 	type args struct {
-		path string
-		url  string
+		url        string
+		customerID string
+		headers    map[string]string
 	}
 	tests := []struct {
-		name         string
-		args         args
-		wantPathHint string
+		name           string
+		resolver       func(r *http.Request) string
+		args           args
+		wantCustomerID string
 	}{
 		{
-			name: "captures simple path hint from chi",
+			name: "captures simple path hint from mux",
+			args: args{
+				url:        "http://test.com/user/1",
+				customerID: "a-customers-id",
+			},
+			wantCustomerID: "a-customers-id",
+		},
+		{
+			name:     "captures customer id from resolver",
+			resolver: speakeasy.CustomerIDFromHeader("X-Customer-ID"),
 			args: args{
-				path: "/user/{id}",
-				url:  "http://test.com/user/1",
+				url:     "http://test.com/user/1",
+				headers: map[string]string{"X-Customer-ID": "a-resolved-customers-id"},
 			},
-			wantPathHint: "/user/{id}",
+			wantCustomerID: "a-resolved-customers-id",
 		},
 		{
-			name: "captures complex path hint from chi",
+			name:     "captures customer id from controller over resolver",
+			resolver: speakeasy.CustomerIDFromHeader("X-Customer-ID"),
 			args: args{
-				path: "/user/{id}/account/*",
-				url:  "http://test.com/user/abcdefg/account/1",
+				url:        "http://test.com/user/1",
+				customerID: "a-customers-id",
+				headers:    map[string]string{"X-Customer-ID": "a-resolved-customers-id"},
 			},
-			wantPathHint: "/user/{id}/account/{wildcard}",
+			wantCustomerID: "a-customers-id",
 		},
 	}
 	for _, tt := range tests {
@@ -525,18 +454,11 @@ func TestSpeakeasy_Middleware_Chi_PathHint_Success(t *testing.T) {
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					assert.Equal(t, tt.wantCustomerID, req.CustomerId)
 					captured = true
 					wg.Done()
 				}),
-			})
-
-			r := chi.NewRouter()
-			r.Use(sdkInstance.Middleware)
-
-			r.Get(tt.args.path, func(w http.ResponseWriter, req *http.Request) {
-				w.WriteHeader(http.StatusOK)
-				handled = true
+				CustomerIDResolver: tt.resolver,
 			})
 
 			w := httptest.NewRecorder()
@@ -544,7 +466,20 @@ func TestSpeakeasy_Middleware_Chi_PathHint_Success(t *testing.T) {
 			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
 			assert.NoError(t, err)
 
-			r.ServeHTTP(w, req)
+			for k, v := range tt.args.headers {
+				req.Header.Set(k, v)
+			}
+
+			sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				require.NotNil(t, ctrl)
+				if tt.args.customerID != "" {
+					ctrl.CustomerID(tt.args.customerID)
+				}
+
+				w.WriteHeader(http.StatusOK)
+				handled = true
+			})).ServeHTTP(w, req)
 
 			wg.Wait()
 
@@ -555,27 +490,52 @@ func TestSpeakeasy_Middleware_Chi_PathHint_Success(t *testing.T) {
 	}
 }
 
-func TestSpeakeasy_Middleware_ServerMux_PathHint_Success(t *testing.T) {
+func TestSpeakeasy_Middleware_Capture_OpenAPIPathHint_Success(t *testing.T) {
 	type args struct {
-		path string
-		url  string
+		Method string
+		URL    string
+		Doc    string
 	}
 	tests := []struct {
-		name         string
-		args         args
-		wantPathHint string
+		Name         string
+		Args         args
+		WantPathHint string
 	}{
 		{
-			name: "captures simple path hint from DefaultServerMux",
-			args: args{
-				path: "/user",
-				url:  "http://test.com/user",
+			Name: "captures simple path hint from OpenAPI",
+			Args: args{
+				Method: http.MethodGet,
+				URL:    "http://test.com/user",
+				Doc: `openapi: 3.0.0
+paths:
+  /user:
+    get:
+      responses:
+        '200':
+          description: OK`,
 			},
-			wantPathHint: "/user",
+			WantPathHint: "/user",
+		},
+		{
+			Name: "captures more complex path hint from OpenAPI",
+			Args: args{
+				Method: http.MethodGet,
+				URL:    "http://test.com/user/1/send",
+				Doc: `openapi: 3.0.0
+paths:
+  /user/{id}/{action}:
+    get:
+      responses:
+        '200':
+          description: OK`,
+			},
+			WantPathHint: "/user/{id}/{action}",
 		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+		t.Run(tt.Name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+
 			captured := false
 			handled := false
 
@@ -590,741 +550,1303 @@ func TestSpeakeasy_Middleware_ServerMux_PathHint_Success(t *testing.T) {
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					md, ok := metadata.FromIncomingContext(ctx)
+					assert.True(t, ok)
+
+					apiKeys := md.Get("x-api-key")
+					assert.Contains(t, apiKeys, testAPIKey)
+
+					assert.Equal(t, testApiID, req.ApiId)
+					assert.Equal(t, testVersionID, req.VersionId)
+					assert.Equal(t, tt.WantPathHint, req.PathHint)
 					captured = true
 					wg.Done()
 				}),
+				OpenAPIDocument: []byte(tt.Args.Doc),
 			})
 
-			r := http.DefaultServeMux
-
-			r.Handle(tt.args.path, sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				handled = true
-			})))
+			}))
 
 			w := httptest.NewRecorder()
 
-			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			var req *http.Request
+			var err error
+			req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
 			assert.NoError(t, err)
 
-			r.ServeHTTP(w, req)
+			h.ServeHTTP(w, req)
 
 			wg.Wait()
 
 			assert.True(t, handled, "middleware did not call handler")
 			assert.True(t, captured, "middleware did not capture request")
+
 			assert.Equal(t, http.StatusOK, w.Code)
 		})
 	}
 }
 
-func TestSpeakeasy_GinMiddleware_Success(t *testing.T) {
-	tests := loadTestData(t)
-	for _, tt := range tests {
-		t.Run(tt.Name, func(t *testing.T) {
-			speakeasy.ExportSetMaxCaptureSize(tt.Fields.MaxCaptureSize)
+func TestSpeakeasy_Middleware_Capture_ChunkedTrailers_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	speakeasy.ExportSetTimeSince(1 * time.Millisecond)
 
-			captured := false
-			handled := false
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
 
-			if tt.Args.RequestStartTime.IsZero() {
-				speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			} else {
-				speakeasy.ExportSetTimeNow(tt.Args.RequestStartTime)
-			}
-			if tt.Args.ElapsedTime == 0 {
-				speakeasy.ExportSetTimeSince(1 * time.Millisecond)
-			} else {
-				speakeasy.ExportSetTimeSince(time.Duration(tt.Args.ElapsedTime) * time.Millisecond)
-			}
+	var captured *har.Entry
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h har.HAR
 
-			sdkInstance := speakeasy.New(speakeasy.Config{
-				APIKey:    testAPIKey,
-				ApiID:     testApiID,
-				VersionID: testVersionID,
-				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.JSONEq(t, tt.WantHAR, req.Har)
-					captured = true
-					wg.Done()
-				}),
-			})
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
 
-			r := gin.Default()
-			r.Use(sdkInstance.GinMiddleware)
+			captured = h.Log.Entries[0]
+			wg.Done()
+		}),
+	})
 
-			r.Any("/*path", func(ctx *gin.Context) {
-				ctrl, _ := speakeasy.MiddlewareController(ctx.Request)
+	s := httptest.NewServer(sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctrl, _ := speakeasy.MiddlewareController(req)
+		ctrl.Masking(speakeasy.WithResponseHeaderMask([]string{"X-Secret"}))
 
-				if tt.Args.QueryStringMasks != nil {
-					for k, v := range tt.Args.QueryStringMasks {
-						ctrl.Masking(speakeasy.WithQueryStringMask([]string{k}, v))
-					}
-				}
+		assert.Equal(t, int64(-1), req.ContentLength)
 
-				if tt.Args.RequestHeaderMasks != nil {
-					for k, v := range tt.Args.RequestHeaderMasks {
-						ctrl.Masking(speakeasy.WithRequestHeaderMask([]string{k}, v))
-					}
-				}
+		data, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "chunked request", string(data))
 
-				if tt.Args.RequestCookieMasks != nil {
-					for k, v := range tt.Args.RequestCookieMasks {
-						ctrl.Masking(speakeasy.WithRequestCookieMask([]string{k}, v))
-					}
-				}
+		w.Header().Set("Trailer", "X-Checksum, X-Secret")
+		w.WriteHeader(http.StatusOK)
 
-				if tt.Args.RequestFieldMasksString != nil {
-					for k, v := range tt.Args.RequestFieldMasksString {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskString([]string{k}, v))
-					}
-				}
+		_, err = w.Write([]byte("chunked "))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		_, err = w.Write([]byte("response"))
+		assert.NoError(t, err)
 
-				if tt.Args.RequestFieldMasksNumber != nil {
-					for k, v := range tt.Args.RequestFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskNumber([]string{k}, v))
-					}
-				}
+		w.Header().Set("X-Checksum", "abc123")
+		w.Header().Set("X-Secret", "secret")
+		w.Header().Set(http.TrailerPrefix+"X-Undeclared", "undeclared")
+	})))
+	defer s.Close()
 
-				if tt.Args.ResponseHeaderMasks != nil {
-					for k, v := range tt.Args.ResponseHeaderMasks {
-						ctrl.Masking(speakeasy.WithResponseHeaderMask([]string{k}, v))
-					}
-				}
-
-				if tt.Args.ResponseCookieMasks != nil {
-					for k, v := range tt.Args.ResponseCookieMasks {
-						ctrl.Masking(speakeasy.WithResponseCookieMask([]string{k}, v))
-					}
-				}
+	// A body without a known length is sent using chunked transfer encoding
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/test", io.MultiReader(strings.NewReader("chunked "), strings.NewReader("request")))
+	require.NoError(t, err)
+	req.Trailer = http.Header{"X-Request-Checksum": []string{"def456"}}
 
-				if tt.Args.ResponseFieldMasksString != nil {
-					for k, v := range tt.Args.ResponseFieldMasksString {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskString([]string{k}, v))
-					}
-				}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
 
-				if tt.Args.ResponseFieldMasksNumber != nil {
-					for k, v := range tt.Args.ResponseFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskNumber([]string{k}, v))
-					}
-				}
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "chunked response", string(body))
 
-				for _, header := range tt.Args.ResponseHeaders {
-					for _, val := range header.Values {
-						ctx.Writer.Header().Add(header.Key, val)
-					}
-				}
+	wg.Wait()
 
-				if ctx.Request.Body != nil {
-					data, err := io.ReadAll(ctx.Request.Body)
-					assert.NoError(t, err)
-					assert.Equal(t, tt.Args.Body, string(data))
-				}
+	assert.Equal(t, int64(15), captured.Request.BodySize)
+	assert.Equal(t, int64(16), captured.Response.BodySize)
+	assert.Equal(t, int64(16), captured.Response.Content.Size)
+	assert.Equal(t, "chunked response", captured.Response.Content.Text)
 
-				if tt.Args.ResponseStatus > 0 {
-					ctx.Writer.WriteHeader(tt.Args.ResponseStatus)
-				}
+	assert.Contains(t, captured.Request.Headers, &har.NameValuePair{Name: "X-Request-Checksum", Value: "def456", Comment: "trailer"})
+	assert.Contains(t, captured.Response.Headers, &har.NameValuePair{Name: "X-Checksum", Value: "abc123", Comment: "trailer"})
+	assert.Contains(t, captured.Response.Headers, &har.NameValuePair{Name: "X-Secret", Value: speakeasy.DefaultStringMask, Comment: "trailer"})
+	assert.Contains(t, captured.Response.Headers, &har.NameValuePair{Name: "X-Undeclared", Value: "undeclared", Comment: "trailer"})
 
-				if tt.Args.ResponseBody != "" {
-					_, err := ctx.Writer.Write([]byte(tt.Args.ResponseBody))
-					assert.NoError(t, err)
-				}
-				handled = true
-			})
+	for _, header := range captured.Response.Headers {
+		assert.NotContains(t, header.Name, http.TrailerPrefix)
+	}
+}
 
-			w := httptest.NewRecorder()
+func TestSpeakeasy_Middleware_Capture_Timings_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeSince(35 * time.Millisecond)
 
-			var req *http.Request
-			var err error
-			if tt.Args.Body == "" {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
-			} else {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, bytes.NewBuffer([]byte(tt.Args.Body)))
-			}
-			assert.NoError(t, err)
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
 
-			for _, header := range tt.Args.Headers {
-				for _, val := range header.Values {
-					req.Header.Add(header.Key, val)
-				}
+	mu := sync.Mutex{}
+	now := startTime
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	speakeasy.ExportSetTimeNowFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	defer speakeasy.ExportSetTimeNow(startTime)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	var timings map[string]float64
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []struct {
+						Timings map[string]float64 `json:"timings"`
+					} `json:"entries"`
+				} `json:"log"`
 			}
 
-			r.ServeHTTP(w, req)
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
 
-			wg.Wait()
+			timings = h.Log.Entries[0].Timings
+			wg.Done()
+		}),
+	})
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
+	w := httptest.NewRecorder()
 
-			responseStatus := http.StatusOK
-			if tt.Args.ResponseStatus > 0 {
-				responseStatus = tt.Args.ResponseStatus
-			}
+	req, err := http.NewRequest(http.MethodPost, "http://test.com/test", strings.NewReader("request"))
+	require.NoError(t, err)
 
-			assert.Equal(t, responseStatus, w.Code)
-		})
-	}
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		advance(5 * time.Millisecond)
+		_, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+
+		advance(10 * time.Millisecond)
+		w.Header().Set("Server-Timing", `db;dur=53, app;dur=47.2;desc="App, Logic", miss`)
+		_, err = w.Write([]byte("first"))
+		assert.NoError(t, err)
+
+		advance(20 * time.Millisecond)
+		_, err = w.Write([]byte("last"))
+		assert.NoError(t, err)
+	})).ServeHTTP(w, req)
+
+	wg.Wait()
+
+	assert.Equal(t, map[string]float64{
+		"send":    5,
+		"wait":    10,
+		"receive": 20,
+		"_db":     53,
+		"_app":    47.2,
+	}, timings)
 }
 
-func TestSpeakeasy_GinMiddleware_PathHint_Success(t *testing.T) {
+func TestSpeakeasy_Middleware_Capture_CaptureRules_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
 	type args struct {
-		path    string
-		url     string
-		devHint string
+		status         int
+		latency        time.Duration
+		responseHeader string
+		forceCapture   bool
 	}
 	tests := []struct {
 		name         string
+		captureRules []speakeasy.CaptureRule
 		args         args
-		wantPathHint string
+		wantCaptured bool
 	}{
 		{
-			name: "captures simple path hint from gin",
-			args: args{
-				path: "/user",
-				url:  "http://test.com/user",
-			},
-			wantPathHint: "/user",
+			name:         "captures unsampled request matching status class",
+			captureRules: []speakeasy.CaptureRule{{StatusClasses: []int{5}}},
+			args:         args{status: http.StatusInternalServerError},
+			wantCaptured: true,
 		},
 		{
-			name: "captures more complex path hint from gin",
-			args: args{
-				path: "/user/:id/*action",
-				url:  "http://test.com/user/1/send",
-			},
-			wantPathHint: "/user/{id}/{action}",
+			name:         "doesn't capture unsampled request not matching status class",
+			captureRules: []speakeasy.CaptureRule{{StatusClasses: []int{5}}},
+			args:         args{status: http.StatusOK},
+			wantCaptured: false,
 		},
 		{
-			name: "path hint is overridden by dev hint",
-			args: args{
-				path:    "/user/:id/*action",
-				url:     "http://test.com/user/1/sent",
-				devHint: "/user/{id}/{action}",
-			},
-			wantPathHint: "/user/{id}/{action}",
+			name:         "captures unsampled request exceeding latency threshold",
+			captureRules: []speakeasy.CaptureRule{{MinLatency: time.Second}},
+			args:         args{status: http.StatusOK, latency: 2 * time.Second},
+			wantCaptured: true,
+		},
+		{
+			name:         "doesn't capture unsampled request below latency threshold",
+			captureRules: []speakeasy.CaptureRule{{MinLatency: time.Second}},
+			args:         args{status: http.StatusOK, latency: 10 * time.Millisecond},
+			wantCaptured: false,
+		},
+		{
+			name:         "captures unsampled request with response header",
+			captureRules: []speakeasy.CaptureRule{{ResponseHeader: "X-Debug"}},
+			args:         args{status: http.StatusOK, responseHeader: "X-Debug"},
+			wantCaptured: true,
+		},
+		{
+			name:         "requires all conditions of a rule to match",
+			captureRules: []speakeasy.CaptureRule{{StatusClasses: []int{4, 5}, ResponseHeader: "X-Debug"}},
+			args:         args{status: http.StatusBadRequest},
+			wantCaptured: false,
+		},
+		{
+			name:         "captures unsampled request forced by controller",
+			captureRules: []speakeasy.CaptureRule{{StatusClasses: []int{5}}, {Forced: true}},
+			args:         args{status: http.StatusOK, forceCapture: true},
+			wantCaptured: true,
+		},
+		{
+			name:         "doesn't capture forced request without forced rule",
+			captureRules: []speakeasy.CaptureRule{{StatusClasses: []int{5}}},
+			args:         args{status: http.StatusOK, forceCapture: true},
+			wantCaptured: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			captured := false
-			handled := false
-
-			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+			speakeasy.ExportSetTimeSince(tt.args.latency)
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+			captured := false
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.Equal(t, tt.wantPathHint, req.PathHint)
 					captured = true
-					wg.Done()
 				}),
+				Sampling: &speakeasy.SamplingConfig{
+					Rate:         0,
+					CaptureRules: tt.captureRules,
+				},
 			})
 
-			r := gin.Default()
-			r.Use(sdkInstance.GinMiddleware)
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+			require.NoError(t, err)
+
+			sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tt.args.forceCapture {
+					ctrl, _ := speakeasy.MiddlewareController(req)
+					ctrl.ForceCapture()
+				}
 
-			r.Handle(http.MethodGet, tt.args.path, func(ctx *gin.Context) {
-				if tt.args.devHint != "" {
-					ctrl, _ := speakeasy.MiddlewareController(ctx.Request)
-					require.NotNil(t, ctrl)
-					ctrl.PathHint(tt.args.devHint)
+				if tt.args.responseHeader != "" {
+					w.Header().Set(tt.args.responseHeader, "true")
 				}
-				ctx.Writer.WriteHeader(http.StatusOK)
-				handled = true
-			})
 
-			w := httptest.NewRecorder()
+				w.WriteHeader(tt.args.status)
+				_, err := w.Write([]byte("body"))
+				assert.NoError(t, err)
+			})).ServeHTTP(w, req)
 
-			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
-			assert.NoError(t, err)
+			assert.Equal(t, tt.args.status, w.Code)
+			assert.Equal(t, "body", w.Body.String())
+			assert.Equal(t, tt.wantCaptured, captured)
+		})
+	}
+}
 
-			r.ServeHTTP(w, req)
+func TestSpeakeasy_Middleware_RequestFilters_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
 
-			wg.Wait()
+	middlewares := map[string]func(sdkInstance *speakeasy.Speakeasy, handler http.HandlerFunc) http.Handler{
+		"Middleware": func(sdkInstance *speakeasy.Speakeasy, handler http.HandlerFunc) http.Handler {
+			return sdkInstance.Middleware(handler)
+		},
+		"MiddlewareWithMux": func(sdkInstance *speakeasy.Speakeasy, handler http.HandlerFunc) http.Handler {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", handler)
+			return sdkInstance.MiddlewareWithMux(mux, mux)
+		},
+	}
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		wantCaptured bool
+	}{
+		{
+			name:         "captures request not matching filters",
+			method:       http.MethodGet,
+			url:          "http://test.com/user",
+			wantCaptured: true,
+		},
+		{
+			name:         "doesn't capture excluded path",
+			method:       http.MethodGet,
+			url:          "http://test.com/healthz",
+			wantCaptured: false,
+		},
+		{
+			name:         "doesn't capture excluded method",
+			method:       http.MethodOptions,
+			url:          "http://test.com/user",
+			wantCaptured: false,
+		},
+		{
+			name:         "doesn't capture request rejected by callback",
+			method:       http.MethodGet,
+			url:          "http://test.com/user?nocapture=true",
+			wantCaptured: false,
+		},
+	}
+	for middlewareName, middleware := range middlewares {
+		for _, tt := range tests {
+			t.Run(middlewareName+"/"+tt.name, func(t *testing.T) {
+				speakeasy.ExportSetMaxCaptureSize(9437184)
+
+				captured := false
+
+				sdkInstance := speakeasy.New(speakeasy.Config{
+					APIKey:    testAPIKey,
+					ApiID:     testApiID,
+					VersionID: testVersionID,
+					GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+						captured = true
+					}),
+					ExcludeRequests: []speakeasy.RequestFilter{
+						{Paths: []string{"/healthz", "/metrics"}},
+						{Methods: []string{http.MethodOptions}},
+					},
+					ShouldCapture: func(r *http.Request) bool {
+						return r.URL.Query().Get("nocapture") == ""
+					},
+				})
+
+				handled := false
+
+				h := middleware(sdkInstance, func(w http.ResponseWriter, req *http.Request) {
+					_, ok := speakeasy.MiddlewareController(req)
+					assert.True(t, ok)
 
-			assert.Equal(t, http.StatusOK, w.Code)
-		})
+					handled = true
+					_, err := w.Write([]byte("body"))
+					assert.NoError(t, err)
+				})
+
+				w := httptest.NewRecorder()
+
+				req, err := http.NewRequest(tt.method, tt.url, nil)
+				require.NoError(t, err)
+
+				h.ServeHTTP(w, req)
+
+				assert.True(t, handled)
+				assert.Equal(t, "body", w.Body.String())
+				assert.Equal(t, tt.wantCaptured, captured)
+			})
+		}
 	}
 }
 
-func TestSpeakeasy_EchoMiddleware_Success(t *testing.T) {
-	tests := loadTestData(t)
-	for _, tt := range tests {
-		t.Run(tt.Name, func(t *testing.T) {
-			speakeasy.ExportSetMaxCaptureSize(tt.Fields.MaxCaptureSize)
+func TestSpeakeasy_Middleware_SkipCapture_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
 
-			captured := false
-			handled := false
+	captured := false
 
-			if tt.Args.RequestStartTime.IsZero() {
-				speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			} else {
-				speakeasy.ExportSetTimeNow(tt.Args.RequestStartTime)
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			captured = true
+		}),
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodPost, "http://test.com/test", strings.NewReader(`{"ssn": "123-45-6789"}`))
+	require.NoError(t, err)
+
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctrl, _ := speakeasy.MiddlewareController(req)
+		ctrl.SkipCapture()
+
+		_, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+
+		_, err = w.Write([]byte("body"))
+		assert.NoError(t, err)
+	})).ServeHTTP(w, req)
+
+	assert.Equal(t, "body", w.Body.String())
+	assert.False(t, captured)
+}
+
+func TestSpeakeasy_Middleware_SkipBodies_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	var entry struct {
+		Request struct {
+			BodySize int64            `json:"bodySize"`
+			PostData *json.RawMessage `json:"postData"`
+		} `json:"request"`
+		Response struct {
+			Status   int   `json:"status"`
+			BodySize int64 `json:"bodySize"`
+			Headers  []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"headers"`
+			Content struct {
+				Size int64  `json:"size"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+		BodiesSkipped bool `json:"_bodiesSkipped"`
+	}
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []json.RawMessage `json:"entries"`
+				} `json:"log"`
 			}
-			if tt.Args.ElapsedTime == 0 {
-				speakeasy.ExportSetTimeSince(1 * time.Millisecond)
-			} else {
-				speakeasy.ExportSetTimeSince(time.Duration(tt.Args.ElapsedTime) * time.Millisecond)
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+			require.Len(t, h.Log.Entries, 1)
+
+			assert.NotContains(t, string(h.Log.Entries[0]), "123-45-6789")
+
+			err = json.Unmarshal(h.Log.Entries[0], &entry)
+			require.NoError(t, err)
+		}),
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodPost, "http://test.com/test", strings.NewReader(`{"ssn": "123-45-6789"}`))
+	require.NoError(t, err)
+
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctrl, _ := speakeasy.MiddlewareController(req)
+		ctrl.SkipBodies()
+
+		_, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write([]byte(`{"ssn": "123-45-6789"}`))
+		assert.NoError(t, err)
+	})).ServeHTTP(w, req)
+
+	assert.Equal(t, `{"ssn": "123-45-6789"}`, w.Body.String())
+
+	assert.True(t, entry.BodiesSkipped)
+	assert.Equal(t, int64(22), entry.Request.BodySize)
+	assert.Nil(t, entry.Request.PostData)
+	assert.Equal(t, http.StatusCreated, entry.Response.Status)
+	assert.Equal(t, int64(22), entry.Response.BodySize)
+	assert.Equal(t, int64(22), entry.Response.Content.Size)
+	assert.Empty(t, entry.Response.Content.Text)
+	assert.Contains(t, entry.Response.Headers, struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}{Name: "Content-Type", Value: "application/json"})
+}
+
+func TestSpeakeasy_Middleware_FieldMasks_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	var entry struct {
+		Request struct {
+			PostData struct {
+				Text string `json:"text"`
+			} `json:"postData"`
+		} `json:"request"`
+		Response struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+	}
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []json.RawMessage `json:"entries"`
+				} `json:"log"`
 			}
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+			require.Len(t, h.Log.Entries, 1)
+
+			err = json.Unmarshal(h.Log.Entries[0], &entry)
+			require.NoError(t, err)
+		}),
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodPost, "http://test.com/test", strings.NewReader(`{"password": "secret", "pin": 1234, "token": "abc"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctrl, _ := speakeasy.MiddlewareController(req)
+		ctrl.Masking(
+			speakeasy.WithRequestFieldMaskString([]string{"password"}),
+			speakeasy.WithRequestFieldMaskNumber([]string{"pin"}),
+			speakeasy.WithResponseFieldMaskString([]string{"token"}),
+		)
+
+		_, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte(`{"password": "secret", "pin": 1234, "token": "abc"}`))
+		assert.NoError(t, err)
+	})).ServeHTTP(w, req)
+
+	// request bodies are masked by the request field masks and response bodies by the response field masks
+	assert.Equal(t, `{"password": "__masked__", "pin": -12321, "token": "abc"}`, entry.Request.PostData.Text)
+	assert.Equal(t, `{"password": "secret", "pin": 1234, "token": "__masked__"}`, entry.Response.Content.Text)
+}
+
+func TestSpeakeasy_Middleware_Capture_Panic_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	tests := []struct {
+		name        string
+		writeStatus int
+		wantStatus  int
+	}{
+		{
+			name:       "captures 500 when panicking before writing status",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "captures written status when panicking after writing status",
+			writeStatus: http.StatusAccepted,
+			wantStatus:  http.StatusAccepted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+
+			var entry struct {
+				Response struct {
+					Status int `json:"status"`
+				} `json:"response"`
+				Panic struct {
+					Value string `json:"value"`
+					Stack string `json:"stack"`
+				} `json:"_panic"`
+			}
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.JSONEq(t, tt.WantHAR, req.Har)
-					captured = true
-					wg.Done()
+					var h struct {
+						Log struct {
+							Entries []json.RawMessage `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					err = json.Unmarshal(h.Log.Entries[0], &entry)
+					require.NoError(t, err)
 				}),
 			})
 
-			r := echo.New()
-			r.Use(sdkInstance.EchoMiddleware)
+			recovered := false
 
-			r.Any("/*", func(c echo.Context) error {
-				ctrl, _ := speakeasy.MiddlewareController(c.Request())
+			// the user's own recovery middleware should still see the panic
+			recoverer := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer func() {
+						if p := recover(); p != nil {
+							recovered = true
+							assert.Equal(t, "something went wrong", p)
+						}
+					}()
 
-				if tt.Args.QueryStringMasks != nil {
-					for k, v := range tt.Args.QueryStringMasks {
-						ctrl.Masking(speakeasy.WithQueryStringMask([]string{k}, v))
-					}
-				}
+					next.ServeHTTP(w, r)
+				})
+			}
 
-				if tt.Args.RequestHeaderMasks != nil {
-					for k, v := range tt.Args.RequestHeaderMasks {
-						ctrl.Masking(speakeasy.WithRequestHeaderMask([]string{k}, v))
-					}
-				}
+			w := httptest.NewRecorder()
 
-				if tt.Args.RequestCookieMasks != nil {
-					for k, v := range tt.Args.RequestCookieMasks {
-						ctrl.Masking(speakeasy.WithRequestCookieMask([]string{k}, v))
-					}
-				}
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+			require.NoError(t, err)
 
-				if tt.Args.RequestFieldMasksString != nil {
-					for k, v := range tt.Args.RequestFieldMasksString {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskString([]string{k}, v))
-					}
+			recoverer(sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tt.writeStatus != 0 {
+					w.WriteHeader(tt.writeStatus)
 				}
 
-				if tt.Args.RequestFieldMasksNumber != nil {
-					for k, v := range tt.Args.RequestFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithRequestFieldMaskNumber([]string{k}, v))
-					}
-				}
+				panic("something went wrong")
+			}))).ServeHTTP(w, req)
 
-				if tt.Args.ResponseHeaderMasks != nil {
-					for k, v := range tt.Args.ResponseHeaderMasks {
-						ctrl.Masking(speakeasy.WithResponseHeaderMask([]string{k}, v))
-					}
-				}
+			assert.True(t, recovered)
+			assert.Equal(t, tt.wantStatus, entry.Response.Status)
+			assert.Equal(t, "something went wrong", entry.Panic.Value)
+			assert.Contains(t, entry.Panic.Stack, "TestSpeakeasy_Middleware_Capture_Panic_Success")
+		})
+	}
+}
 
-				if tt.Args.ResponseCookieMasks != nil {
-					for k, v := range tt.Args.ResponseCookieMasks {
-						ctrl.Masking(speakeasy.WithResponseCookieMask([]string{k}, v))
-					}
-				}
+func TestSpeakeasy_Middleware_Capture_Connection_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	var entry struct {
+		ServerIPAddress string `json:"serverIPAddress"`
+		Connection      string `json:"connection"`
+		ClientIPAddress string `json:"_clientIPAddress"`
+		TLS             struct {
+			Version     string `json:"version"`
+			CipherSuite string `json:"cipherSuite"`
+			ALPN        string `json:"alpn"`
+			ServerName  string `json:"serverName"`
+		} `json:"_tls"`
+	}
 
-				if tt.Args.ResponseFieldMasksString != nil {
-					for k, v := range tt.Args.ResponseFieldMasksString {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskString([]string{k}, v))
-					}
-				}
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []json.RawMessage `json:"entries"`
+				} `json:"log"`
+			}
 
-				if tt.Args.ResponseFieldMasksNumber != nil {
-					for k, v := range tt.Args.ResponseFieldMasksNumber {
-						ctrl.Masking(speakeasy.WithResponseFieldMaskNumber([]string{k}, v))
-					}
-				}
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
 
-				for _, header := range tt.Args.ResponseHeaders {
-					for _, val := range header.Values {
-						c.Response().Header().Add(header.Key, val)
-					}
-				}
+			err = json.Unmarshal(h.Log.Entries[0], &entry)
+			require.NoError(t, err)
 
-				if c.Request().Body != nil {
-					data, err := io.ReadAll(c.Request().Body)
-					assert.NoError(t, err)
-					assert.Equal(t, tt.Args.Body, string(data))
-				}
+			wg.Done()
+		}),
+	})
 
-				if tt.Args.ResponseStatus > 0 {
-					c.Response().WriteHeader(tt.Args.ResponseStatus)
-				}
+	var clientPort string
 
-				if tt.Args.ResponseBody != "" {
-					_, err := c.Response().Write([]byte(tt.Args.ResponseBody))
-					assert.NoError(t, err)
-				}
-				handled = true
+	s := httptest.NewUnstartedServer(sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, clientPort, _ = net.SplitHostPort(req.RemoteAddr)
+		w.WriteHeader(http.StatusOK)
+	})))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
 
-				return nil
-			})
+	client := s.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com"
 
-			w := httptest.NewRecorder()
+	res, err := client.Get(s.URL)
+	require.NoError(t, err)
+	res.Body.Close()
 
-			var req *http.Request
-			var err error
-			if tt.Args.Body == "" {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
-			} else {
-				req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, bytes.NewBuffer([]byte(tt.Args.Body)))
-			}
-			assert.NoError(t, err)
+	wg.Wait()
 
-			for _, header := range tt.Args.Headers {
-				for _, val := range header.Values {
-					req.Header.Add(header.Key, val)
-				}
-			}
+	assert.Equal(t, "127.0.0.1", entry.ServerIPAddress)
+	assert.Equal(t, clientPort, entry.Connection)
+	assert.Equal(t, "127.0.0.1", entry.ClientIPAddress)
+	assert.Equal(t, "TLS 1.3", entry.TLS.Version)
+	assert.NotEmpty(t, entry.TLS.CipherSuite)
+	assert.Equal(t, "h2", entry.TLS.ALPN)
+	assert.Equal(t, "example.com", entry.TLS.ServerName)
+}
 
-			r.ServeHTTP(w, req)
+type requestIDContextKey struct{}
 
-			wg.Wait()
+func TestSpeakeasy_Middleware_RequestID_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
+	tests := []struct {
+		name          string
+		config        *speakeasy.RequestIDConfig
+		headers       map[string]string
+		contextID     string
+		wantRequestID string
+		wantGenerated bool
+	}{
+		{
+			name:          "doesn't record request id without config",
+			headers:       map[string]string{"X-Request-ID": "abc123"},
+			wantRequestID: "",
+		},
+		{
+			name:          "records request id from first matching header",
+			config:        &speakeasy.RequestIDConfig{Headers: []string{"X-Request-ID", "X-Correlation-ID"}},
+			headers:       map[string]string{"X-Correlation-ID": "corr-1", "X-Request-ID": "abc123"},
+			wantRequestID: "abc123",
+		},
+		{
+			name:          "falls back to later headers",
+			config:        &speakeasy.RequestIDConfig{Headers: []string{"X-Request-ID", "X-Correlation-ID"}},
+			headers:       map[string]string{"X-Correlation-ID": "corr-1"},
+			wantRequestID: "corr-1",
+		},
+		{
+			name: "records request id from context before headers",
+			config: &speakeasy.RequestIDConfig{
+				Headers: []string{"X-Request-ID"},
+				FromContext: func(ctx context.Context) string {
+					id, _ := ctx.Value(requestIDContextKey{}).(string)
+					return id
+				},
+			},
+			headers:       map[string]string{"X-Request-ID": "abc123"},
+			contextID:     "ctx-1",
+			wantRequestID: "ctx-1",
+		},
+		{
+			name:          "generates request id if absent",
+			config:        &speakeasy.RequestIDConfig{Headers: []string{"X-Request-ID"}},
+			wantGenerated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+
+			var capturedID string
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					var h struct {
+						Log struct {
+							Entries []struct {
+								RequestID string `json:"_requestId"`
+							} `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					capturedID = h.Log.Entries[0].RequestID
+				}),
+				RequestID: tt.config,
+			})
+
+			var handlerID string
+
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				handlerID = ctrl.RequestID()
+
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+			require.NoError(t, err)
 
-			responseStatus := http.StatusOK
-			if tt.Args.ResponseStatus > 0 {
-				responseStatus = tt.Args.ResponseStatus
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
 			}
+			if tt.contextID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, tt.contextID))
+			}
+
+			h.ServeHTTP(w, req)
 
-			assert.Equal(t, responseStatus, w.Code)
+			assert.Equal(t, handlerID, capturedID)
+			if tt.wantGenerated {
+				assert.Len(t, capturedID, 36)
+			} else {
+				assert.Equal(t, tt.wantRequestID, capturedID)
+			}
 		})
 	}
 }
 
-func TestSpeakeasy_EchoMiddleware_PathHint_Success(t *testing.T) {
-	type args struct {
-		path    string
-		url     string
-		devHint string
-	}
+func TestSpeakeasy_Middleware_Metadata_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
 	tests := []struct {
 		name         string
-		args         args
-		wantPathHint string
+		tags         map[string]string
+		handlerTags  map[string]string
+		metadata     map[string]string
+		wantMetadata map[string]string
 	}{
 		{
-			name: "captures simple path hint from echo",
-			args: args{
-				path: "/user",
-				url:  "http://test.com/user",
+			name: "doesn't record metadata if none provided",
+		},
+		{
+			name: "records default tags",
+			tags: map[string]string{"environment": "production", "build": "abc123"},
+			wantMetadata: map[string]string{
+				"environment": "production",
+				"build":       "abc123",
 			},
-			wantPathHint: "/user",
 		},
 		{
-			name: "captures more complex path hint from echo",
-			args: args{
-				path: "/user/:id/*",
-				url:  "http://test.com/user/1/send",
+			name:        "records controller tags and metadata",
+			handlerTags: map[string]string{"tenant": "acme"},
+			metadata:    map[string]string{"plan": "enterprise", "region": "eu-west-1"},
+			wantMetadata: map[string]string{
+				"tenant": "acme",
+				"plan":   "enterprise",
+				"region": "eu-west-1",
 			},
-			wantPathHint: "/user/{id}/{wildcard}",
 		},
 		{
-			name: "path hint is overridden by dev hint",
-			args: args{
-				path:    "/user/:id/*action",
-				url:     "http://test.com/user/1/sent",
-				devHint: "/user/{id}/{action}",
+			name:        "controller metadata overrides default tags",
+			tags:        map[string]string{"environment": "production", "region": "us-east-1"},
+			handlerTags: map[string]string{"region": "eu-west-1"},
+			wantMetadata: map[string]string{
+				"environment": "production",
+				"region":      "eu-west-1",
 			},
-			wantPathHint: "/user/{id}/{action}",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			captured := false
-			handled := false
-
-			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+			speakeasy.ExportSetMaxCaptureSize(9437184)
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+			var capturedMetadata map[string]string
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.Equal(t, tt.wantPathHint, req.PathHint)
-					captured = true
-					wg.Done()
+					var h struct {
+						Log struct {
+							Entries []struct {
+								Metadata map[string]string `json:"_metadata"`
+							} `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					capturedMetadata = h.Log.Entries[0].Metadata
 				}),
+				Tags: tt.tags,
 			})
 
-			r := echo.New()
-			r.Use(sdkInstance.EchoMiddleware)
-			r.Match([]string{http.MethodGet}, tt.args.path, func(c echo.Context) error {
-				if tt.args.devHint != "" {
-					ctrl, _ := speakeasy.MiddlewareController(c.Request())
-					require.NotNil(t, ctrl)
-					ctrl.PathHint(tt.args.devHint)
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				for k, v := range tt.handlerTags {
+					ctrl.Tag(k, v)
+				}
+				if tt.metadata != nil {
+					ctrl.Metadata(tt.metadata)
 				}
-				c.Response().Writer.WriteHeader(http.StatusOK)
-				handled = true
 
-				return nil
-			})
+				w.WriteHeader(http.StatusOK)
+			}))
 
 			w := httptest.NewRecorder()
 
-			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
-			assert.NoError(t, err)
-
-			r.ServeHTTP(w, req)
-
-			wg.Wait()
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+			require.NoError(t, err)
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
+			h.ServeHTTP(w, req)
 
-			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantMetadata, capturedMetadata)
 		})
 	}
 }
 
-func TestSpeakeasy_Middleware_Capture_CustomerID_Success(t *testing.T) {
-	type args struct {
-		url        string
-		customerID string
-	}
+func TestSpeakeasy_Middleware_GraphQL_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
 	tests := []struct {
-		name           string
-		args           args
-		wantCustomerID string
+		name             string
+		config           *speakeasy.GraphQLConfig
+		url              string
+		body             string
+		masks            []string
+		stringFieldMasks []string
+		numberFieldMasks []string
+		wantPathHint     string
+		wantGraphQL      string
+		wantPostData     string
 	}{
 		{
-			name: "captures simple path hint from mux",
-			args: args{
-				url:        "http://test.com/user/1",
-				customerID: "a-customers-id",
-			},
-			wantCustomerID: "a-customers-id",
+			name:         "doesn't resolve operation without config",
+			url:          "http://test.com/graphql",
+			body:         `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`,
+			wantPathHint: "",
+			wantPostData: `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`,
+		},
+		{
+			name:         "resolves named query",
+			config:       &speakeasy.GraphQLConfig{},
+			url:          "http://test.com/graphql",
+			body:         `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`,
+			wantPathHint: "/graphql/query/GetUser",
+			wantGraphQL:  `{"operationType":"query","operationName":"GetUser","variables":{"id":"1"}}`,
+			wantPostData: `{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`,
+		},
+		{
+			name:         "resolves anonymous query",
+			config:       &speakeasy.GraphQLConfig{},
+			url:          "http://test.com/graphql",
+			body:         `{"query":"{ users { name } }"}`,
+			wantPathHint: "/graphql/query",
+			wantGraphQL:  `{"operationType":"query"}`,
+			wantPostData: `{"query":"{ users { name } }"}`,
+		},
+		{
+			name:         "resolves operation by name from document with multiple operations",
+			config:       &speakeasy.GraphQLConfig{Paths: []string{"/api/graphql"}},
+			url:          "http://test.com/api/graphql",
+			body:         `{"query":"query GetUser { user { name } } mutation UpdateUser { updateUser { name } }","operationName":"UpdateUser"}`,
+			wantPathHint: "/api/graphql/mutation/UpdateUser",
+			wantGraphQL:  `{"operationType":"mutation","operationName":"UpdateUser"}`,
+			wantPostData: `{"query":"query GetUser { user { name } } mutation UpdateUser { updateUser { name } }","operationName":"UpdateUser"}`,
+		},
+		{
+			name:         "doesn't append operation name over max length",
+			config:       &speakeasy.GraphQLConfig{},
+			url:          "http://test.com/graphql",
+			body:         `{"query":"query GetUserUserUserUserUserUserUserUserUserUserUserUserUserUserUserUser { user { name } }"}`,
+			wantPathHint: "/graphql/query",
+			wantGraphQL:  `{"operationType":"query","operationName":"GetUserUserUserUserUserUserUserUserUserUserUserUserUserUserUserUser"}`,
+			wantPostData: `{"query":"query GetUserUserUserUserUserUserUserUserUserUserUserUserUserUserUserUser { user { name } }"}`,
+		},
+		{
+			name:         "doesn't resolve operation on other paths",
+			config:       &speakeasy.GraphQLConfig{},
+			url:          "http://test.com/users",
+			body:         `{"query":"query GetUser { user { name } }"}`,
+			wantPathHint: "",
+			wantPostData: `{"query":"query GetUser { user { name } }"}`,
+		},
+		{
+			name:         "masks variables",
+			config:       &speakeasy.GraphQLConfig{},
+			url:          "http://test.com/graphql",
+			body:         `{"query":"mutation Login($email: String!, $password: String!) { login(email: $email, password: $password) { token } }","variables":{"email":"test@test.com","password":{"value":"secret"}}}`,
+			masks:        []string{"password"},
+			wantPathHint: "/graphql/mutation/Login",
+			wantGraphQL:  `{"operationType":"mutation","operationName":"Login","variables":{"email":"test@test.com","password":"__masked__"}}`,
+			wantPostData: `{"query":"mutation Login($email: String!, $password: String!) { login(email: $email, password: $password) { token } }","variables":{"email":"test@test.com","password":"__masked__"}}`,
+		},
+		{
+			name:             "masks variables with request field masks",
+			config:           &speakeasy.GraphQLConfig{},
+			url:              "http://test.com/graphql",
+			body:             `{"query":"mutation Register($input: RegisterInput!, $pin: Int!) { register(input: $input, pin: $pin) { token } }","variables":{"input":{"email":"test@test.com","password":"secret"},"pin":1234}}`,
+			stringFieldMasks: []string{"password"},
+			numberFieldMasks: []string{"pin"},
+			wantPathHint:     "/graphql/mutation/Register",
+			wantGraphQL:      `{"operationType":"mutation","operationName":"Register","variables":{"input":{"email":"test@test.com","password":"__masked__"},"pin":-12321}}`,
+			wantPostData:     `{"query":"mutation Register($input: RegisterInput!, $pin: Int!) { register(input: $input, pin: $pin) { token } }","variables":{"input":{"email":"test@test.com","password":"__masked__"},"pin":-12321}}`,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			captured := false
-			handled := false
-
-			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+			speakeasy.ExportSetMaxCaptureSize(9437184)
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+			captured := false
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					assert.Equal(t, tt.wantCustomerID, req.CustomerId)
+					var h struct {
+						Log struct {
+							Entries []struct {
+								Request struct {
+									PostData struct {
+										Text string `json:"text"`
+									} `json:"postData"`
+								} `json:"request"`
+								GraphQL json.RawMessage `json:"_graphql"`
+							} `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					entry := h.Log.Entries[0]
+
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					assert.JSONEq(t, tt.wantPostData, entry.Request.PostData.Text)
+
+					if tt.wantGraphQL == "" {
+						assert.Nil(t, entry.GraphQL)
+					} else {
+						assert.JSONEq(t, tt.wantGraphQL, string(entry.GraphQL))
+					}
+
 					captured = true
-					wg.Done()
 				}),
+				GraphQL: tt.config,
 			})
 
-			w := httptest.NewRecorder()
-
-			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
-			assert.NoError(t, err)
-
-			sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				ctrl, _ := speakeasy.MiddlewareController(req)
-				require.NotNil(t, ctrl)
-				ctrl.CustomerID(tt.args.customerID)
+				ctrl.Masking(
+					speakeasy.WithGraphQLVariableMask(tt.masks),
+					speakeasy.WithRequestFieldMaskString(tt.stringFieldMasks),
+					speakeasy.WithRequestFieldMaskNumber(tt.numberFieldMasks),
+				)
+
+				_, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
 
 				w.WriteHeader(http.StatusOK)
-				handled = true
-			})).ServeHTTP(w, req)
+			}))
 
-			wg.Wait()
+			w := httptest.NewRecorder()
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
-			assert.Equal(t, http.StatusOK, w.Code)
+			req, err := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			h.ServeHTTP(w, req)
+
+			assert.True(t, captured)
 		})
 	}
 }
 
-func TestSpeakeasy_Middleware_DataDogHttpTraceServerMux_PathHint_Success(t *testing.T) {
-	type args struct {
-		path string
-		url  string
-	}
+func TestSpeakeasy_Middleware_JSONRPC_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
 	tests := []struct {
 		name         string
-		args         args
+		config       *speakeasy.JSONRPCConfig
+		url          string
+		body         string
 		wantPathHint string
+		wantJSONRPC  string
+		wantPostData string
 	}{
 		{
-			name: "captures simple path hint from DefaultServerMux",
-			args: args{
-				path: "/user",
-				url:  "http://test.com/user",
-			},
-			wantPathHint: "/user",
+			name:         "doesn't resolve method without config",
+			url:          "http://test.com/rpc",
+			body:         `{"jsonrpc": "2.0", "method": "getUser", "params": {"id": 1}, "id": 1}`,
+			wantPathHint: "",
+			wantPostData: `{"jsonrpc": "2.0", "method": "getUser", "params": {"id": 1}, "id": 1}`,
+		},
+		{
+			name:         "resolves method",
+			config:       &speakeasy.JSONRPCConfig{},
+			url:          "http://test.com/rpc",
+			body:         `{"jsonrpc": "2.0", "method": "getUser", "params": {"id": 1}, "id": 1}`,
+			wantPathHint: "/rpc/getUser",
+			wantJSONRPC:  `{"methods": ["getUser"]}`,
+			wantPostData: `{"jsonrpc": "2.0", "method": "getUser", "params": {"id": 1}, "id": 1}`,
+		},
+		{
+			name:         "masks params with field masks",
+			config:       &speakeasy.JSONRPCConfig{Paths: []string{"/api/rpc"}},
+			url:          "http://test.com/api/rpc",
+			body:         `{"jsonrpc": "2.0", "method": "login", "params": {"username": "test", "password": "secret"}, "id": 1}`,
+			wantPathHint: "/api/rpc/login",
+			wantJSONRPC:  `{"methods": ["login"]}`,
+			wantPostData: `{"jsonrpc": "2.0", "method": "login", "params": {"username": "test", "password": "__masked__"}, "id": 1}`,
+		},
+		{
+			name:         "tags all methods of batch",
+			config:       &speakeasy.JSONRPCConfig{},
+			url:          "http://test.com/rpc",
+			body:         `[{"jsonrpc": "2.0", "method": "getUser", "id": 1}, {"jsonrpc": "2.0", "method": "notify"}]`,
+			wantPathHint: "/rpc/batch",
+			wantJSONRPC:  `{"methods": ["getUser", "notify"], "batch": true}`,
+			wantPostData: `[{"jsonrpc": "2.0", "method": "getUser", "id": 1}, {"jsonrpc": "2.0", "method": "notify"}]`,
+		},
+		{
+			name:         "doesn't resolve method on other paths",
+			config:       &speakeasy.JSONRPCConfig{Paths: []string{"/rpc"}},
+			url:          "http://test.com/users",
+			body:         `{"jsonrpc": "2.0", "method": "getUser", "id": 1}`,
+			wantPathHint: "",
+			wantPostData: `{"jsonrpc": "2.0", "method": "getUser", "id": 1}`,
+		},
+		{
+			name:         "doesn't append unsafe method",
+			config:       &speakeasy.JSONRPCConfig{},
+			url:          "http://test.com/rpc",
+			body:         `{"jsonrpc": "2.0", "method": "users/{id}", "id": 1}`,
+			wantPathHint: "/rpc",
+			wantJSONRPC:  `{"methods": ["users/{id}"]}`,
+			wantPostData: `{"jsonrpc": "2.0", "method": "users/{id}", "id": 1}`,
+		},
+		{
+			name:         "doesn't resolve method of other versions",
+			config:       &speakeasy.JSONRPCConfig{},
+			url:          "http://test.com/rpc",
+			body:         `{"jsonrpc": "1.0", "method": "getUser", "id": 1}`,
+			wantPathHint: "",
+			wantPostData: `{"jsonrpc": "1.0", "method": "getUser", "id": 1}`,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			captured := false
-			handled := false
-
-			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+			speakeasy.ExportSetMaxCaptureSize(9437184)
 
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
+			captured := false
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					var h struct {
+						Log struct {
+							Entries []struct {
+								Request struct {
+									PostData struct {
+										Text string `json:"text"`
+									} `json:"postData"`
+								} `json:"request"`
+								JSONRPC json.RawMessage `json:"_jsonRpc"`
+							} `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					entry := h.Log.Entries[0]
+
 					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					assert.JSONEq(t, tt.wantPostData, entry.Request.PostData.Text)
+
+					if tt.wantJSONRPC == "" {
+						assert.Nil(t, entry.JSONRPC)
+					} else {
+						assert.JSONEq(t, tt.wantJSONRPC, string(entry.JSONRPC))
+					}
+
 					captured = true
-					wg.Done()
 				}),
+				JSONRPC: tt.config,
 			})
 
-			r := httptrace.NewServeMux()
+			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				ctrl.Masking(speakeasy.WithRequestFieldMaskString([]string{"password"}))
+
+				_, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
 
-			r.Handle(tt.args.path, sdkInstance.MiddlewareWithMux(r, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusOK)
-				handled = true
-			})))
+			}))
 
 			w := httptest.NewRecorder()
 
-			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
-			assert.NoError(t, err)
-
-			r.ServeHTTP(w, req)
+			req, err := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
 
-			wg.Wait()
+			h.ServeHTTP(w, req)
 
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
-			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
 		})
 	}
 }
 
-func TestSpeakeasy_Middleware_Capture_OpenAPIPathHint_Success(t *testing.T) {
-	type args struct {
-		Method string
-		URL    string
-		Doc    string
-	}
+func TestSpeakeasy_Middleware_PathHintResolvers_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
 	tests := []struct {
-		Name         string
-		Args         args
-		WantPathHint string
+		name         string
+		resolvers    []speakeasy.PathHintResolver
+		url          string
+		wantPathHint string
 	}{
 		{
-			Name: "captures simple path hint from OpenAPI",
-			Args: args{
-				Method: http.MethodGet,
-				URL:    "http://test.com/user",
-				Doc: `openapi: 3.0.0
-paths:
-  /user:
-    get:
-      responses:
-        '200':
-          description: OK`,
+			name: "resolves custom path hint ahead of the ServeMux pattern",
+			resolvers: []speakeasy.PathHintResolver{
+				speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+					return ""
+				}),
+				speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+					return "/custom/{id}"
+				}),
 			},
-			WantPathHint: "/user",
+			url:          "http://test.com/users/1",
+			wantPathHint: "/custom/{id}",
 		},
 		{
-			Name: "captures more complex path hint from OpenAPI",
-			Args: args{
-				Method: http.MethodGet,
-				URL:    "http://test.com/user/1/send",
-				Doc: `openapi: 3.0.0
-paths:
-  /user/{id}/{action}:
-    get:
-      responses:
-        '200':
-          description: OK`,
+			name: "falls back to the ServeMux pattern when custom resolvers don't resolve a path hint",
+			resolvers: []speakeasy.PathHintResolver{
+				speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+					return ""
+				}),
 			},
-			WantPathHint: "/user/{id}/{action}",
+			url:          "http://test.com/users/1",
+			wantPathHint: "/users/",
 		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.Name, func(t *testing.T) {
+		t.Run(tt.name, func(t *testing.T) {
 			speakeasy.ExportSetMaxCaptureSize(9437184)
 
 			captured := false
-			handled := false
-
-			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
-			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
-
-			wg := &sync.WaitGroup{}
-			wg.Add(1)
 
 			sdkInstance := speakeasy.New(speakeasy.Config{
 				APIKey:    testAPIKey,
 				ApiID:     testApiID,
 				VersionID: testVersionID,
 				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
-					md, ok := metadata.FromIncomingContext(ctx)
-					assert.True(t, ok)
-
-					apiKeys := md.Get("x-api-key")
-					assert.Contains(t, apiKeys, testAPIKey)
-
-					assert.Equal(t, testApiID, req.ApiId)
-					assert.Equal(t, testVersionID, req.VersionId)
-					assert.Equal(t, tt.WantPathHint, req.PathHint)
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
 					captured = true
-					wg.Done()
 				}),
-				OpenAPIDocument: []byte(tt.Args.Doc),
+				PathHintResolvers: tt.resolvers,
 			})
 
-			h := sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/users/", func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusOK)
-				handled = true
-			}))
+			})
+			h := sdkInstance.MiddlewareWithMux(mux, mux)
 
 			w := httptest.NewRecorder()
 
-			var req *http.Request
-			var err error
-			req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
-			assert.NoError(t, err)
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
 
 			h.ServeHTTP(w, req)
 
-			wg.Wait()
-
-			assert.True(t, handled, "middleware did not call handler")
-			assert.True(t, captured, "middleware did not capture request")
-
 			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
 		})
 	}
 }
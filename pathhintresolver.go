@@ -0,0 +1,29 @@
+package speakeasy
+
+import "net/http"
+
+// PathHintResolver resolves the path hint of a request from the router that matched it, returning an empty string if
+// the request wasn't matched by the router. Resolvers provided in Config.PathHintResolvers are tried in order ahead of the
+// ServeMux patterns used by Middleware and MiddlewareWithMux.
+type PathHintResolver interface {
+	ResolvePathHint(r *http.Request) string
+}
+
+// PathHintResolverFunc allows an ordinary function to be used as a PathHintResolver.
+type PathHintResolverFunc func(r *http.Request) string
+
+// ResolvePathHint calls f(r).
+func (f PathHintResolverFunc) ResolvePathHint(r *http.Request) string {
+	return f(r)
+}
+
+// resolvePathHint returns the path hint of the first of the resolvers to resolve one.
+func resolvePathHint(r *http.Request, resolvers []PathHintResolver) string {
+	for _, resolver := range resolvers {
+		if pathHint := resolver.ResolvePathHint(r); pathHint != "" {
+			return pathHint
+		}
+	}
+
+	return ""
+}
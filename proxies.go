@@ -0,0 +1,121 @@
+package speakeasy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+func mustParseTrustedProxies(proxies []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				panic(fmt.Errorf("invalid IP address %q: %w", proxy, ErrTrustedProxyMalformed))
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(fmt.Errorf("invalid CIDR %q: %w", proxy, ErrTrustedProxyMalformed))
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// isTrustedProxy returns true if the address is a trusted proxy, no addresses are trusted if no trusted proxies were configured.
+func (h *harBuilder) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getClientIP returns the IP address of the client that made the request, if the request was made by a trusted proxy
+// the forwarded headers are walked from the closest proxy until an address that isn't a trusted proxy is found.
+func (h *harBuilder) getClientIP(r *http.Request) string {
+	remoteIP := hostWithoutPort(r.RemoteAddr)
+	if !h.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	forwarded := getForwardedFor(r)
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !h.isTrustedProxy(forwarded[i]) || i == 0 {
+			return forwarded[i]
+		}
+	}
+
+	if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+		return realIP
+	}
+
+	return remoteIP
+}
+
+// getForwardedFor returns the addresses from the X-Forwarded-For header, or the for parameters of the Forwarded header,
+// ordered from the client to the closest proxy.
+func getForwardedFor(r *http.Request) []string {
+	addrs := []string{}
+
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			for _, addr := range strings.Split(value, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					addrs = append(addrs, hostWithoutPort(addr))
+				}
+			}
+		}
+
+		return addrs
+	}
+
+	for _, value := range r.Header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, addr, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found || !strings.EqualFold(key, "for") {
+					continue
+				}
+
+				addr = strings.Trim(addr, `"`)
+				if addr != "" {
+					addrs = append(addrs, hostWithoutPort(addr))
+				}
+			}
+		}
+	}
+
+	return addrs
+}
+
+func hostWithoutPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.Trim(addr, "[]")
+}
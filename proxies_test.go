@@ -0,0 +1,88 @@
+//nolint:testpackage
+package speakeasy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHarBuilder_isTrustedProxy(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		addr           string
+		wantTrusted    bool
+	}{
+		{
+			name:        "trusts no addresses without trusted proxies",
+			addr:        "198.51.100.4",
+			wantTrusted: false,
+		},
+		{
+			name:           "trusts no addresses with empty trusted proxies",
+			trustedProxies: []string{},
+			addr:           "10.0.0.1",
+			wantTrusted:    false,
+		},
+		{
+			name:           "trusts all addresses with catch-all CIDRs",
+			trustedProxies: []string{"0.0.0.0/0", "::/0"},
+			addr:           "198.51.100.4",
+			wantTrusted:    true,
+		},
+		{
+			name:           "trusts address in CIDR",
+			trustedProxies: []string{"10.0.0.0/8", "fd00::/8"},
+			addr:           "10.1.2.3",
+			wantTrusted:    true,
+		},
+		{
+			name:           "trusts IPv6 address in CIDR",
+			trustedProxies: []string{"10.0.0.0/8", "fd00::/8"},
+			addr:           "fd12::1",
+			wantTrusted:    true,
+		},
+		{
+			name:           "trusts single IP address",
+			trustedProxies: []string{"192.0.2.1"},
+			addr:           "192.0.2.1",
+			wantTrusted:    true,
+		},
+		{
+			name:           "doesn't trust address outside of trusted proxies",
+			trustedProxies: []string{"192.0.2.1", "10.0.0.0/8"},
+			addr:           "192.0.2.2",
+			wantTrusted:    false,
+		},
+		{
+			name:           "doesn't trust invalid address",
+			trustedProxies: []string{"10.0.0.0/8"},
+			addr:           "not-an-ip",
+			wantTrusted:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := harBuilder{trustedProxies: mustParseTrustedProxies(tt.trustedProxies)}
+
+			assert.Equal(t, tt.wantTrusted, h.isTrustedProxy(tt.addr))
+		})
+	}
+}
+
+func TestMustParseTrustedProxies_Error(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "localhost"} {
+		t.Run(proxy, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				if assert.True(t, ok) {
+					assert.True(t, errors.Is(err, ErrTrustedProxyMalformed))
+				}
+			}()
+
+			mustParseTrustedProxies([]string{proxy})
+		})
+	}
+}
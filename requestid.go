@@ -0,0 +1,36 @@
+package speakeasy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDConfig configures the ID recorded on captured requests, allowing a capture to be found from the logs of a request.
+type RequestIDConfig struct {
+	// Headers are the request headers checked, in order, for an existing request ID, for example "X-Request-ID".
+	Headers []string
+	// FromContext extracts an existing request ID from the request context, for example one set by another middleware.
+	// It is checked before the Headers, returning an empty string falls back to the Headers.
+	FromContext func(ctx context.Context) string
+}
+
+// resolveRequestID returns the ID of the request, generating one if it wasn't found in the request.
+func (s *Speakeasy) resolveRequestID(r *http.Request) string {
+	cfg := s.config.RequestID
+
+	if cfg.FromContext != nil {
+		if id := cfg.FromContext(r.Context()); id != "" {
+			return id
+		}
+	}
+
+	for _, header := range cfg.Headers {
+		if id := r.Header.Get(header); id != "" {
+			return id
+		}
+	}
+
+	return uuid.NewString()
+}
@@ -0,0 +1,10 @@
+//go:build go1.23
+
+package speakeasy
+
+import "net/http"
+
+// requestPattern returns the net/http ServeMux pattern the request was matched to, available since Go 1.23.
+func requestPattern(r *http.Request) string {
+	return r.Pattern
+}
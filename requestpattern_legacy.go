@@ -0,0 +1,10 @@
+//go:build !go1.23
+
+package speakeasy
+
+import "net/http"
+
+// requestPattern returns an empty pattern as http.Request doesn't record the ServeMux pattern it was matched to before Go 1.23.
+func requestPattern(r *http.Request) string {
+	return ""
+}
@@ -0,0 +1,63 @@
+package speakeasy
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// upstreamRequest records the request made by a reverse proxy to its upstream.
+type upstreamRequest struct {
+	host    string
+	latency time.Duration
+}
+
+// ReverseProxy returns an http.Handler using the default SDK instance to capture requests served by the reverse proxy.
+// See Speakeasy.ReverseProxy for details.
+func ReverseProxy(proxy *httputil.ReverseProxy) http.Handler {
+	return defaultInstance.ReverseProxy(proxy)
+}
+
+// ReverseProxy returns an http.Handler using the current instance of the SDK to capture requests served by the reverse proxy.
+// Each request is captured once, including the response from the upstream as written to the client. The time taken for the
+// upstream to respond is recorded in the _upstream timing of the captured HAR entry and the upstream host is added to its metadata.
+// The proxy isn't modified, its Transport (or http.DefaultTransport) is wrapped by a copy of it.
+func (s *Speakeasy) ReverseProxy(proxy *httputil.ReverseProxy) http.Handler {
+	p := *proxy
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	p.Transport = &upstreamTransport{base: transport}
+
+	return s.Middleware(&p)
+}
+
+// upstreamTransport records the host and latency of requests to the upstream of a reverse proxy on the controller of the proxied request.
+type upstreamTransport struct {
+	base http.RoundTripper
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c, ok := req.Context().Value(controllerKey).(*controller)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	startTime := timeNow()
+
+	res, err := t.base.RoundTrip(req)
+
+	c.setUpstream(&upstreamRequest{
+		host:    req.URL.Host,
+		latency: timeSince(startTime),
+	})
+
+	if err != nil {
+		c.RecordError(err)
+	}
+
+	return res, err
+}
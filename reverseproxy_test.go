@@ -0,0 +1,150 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reverseProxyEntry struct {
+	Request struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"response"`
+	Timings  map[string]float64 `json:"timings"`
+	Metadata map[string]string  `json:"_metadata"`
+	Errors   []struct {
+		Message string `json:"message"`
+	} `json:"_errors"`
+}
+
+func TestSpeakeasy_ReverseProxy_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeSince(25 * time.Millisecond)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/users/1", r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("upstream response"))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	mu := sync.Mutex{}
+	entries := []reverseProxyEntry{}
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: reverseProxyDialer(t, &mu, &entries),
+	})
+
+	proxy := httputil.NewSingleHostReverseProxy(upstreamURL)
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://gateway.com/users/1", nil)
+	require.NoError(t, err)
+
+	sdkInstance.ReverseProxy(proxy).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "upstream response", w.Body.String())
+	assert.Nil(t, proxy.Transport, "proxy should not be modified")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "http://gateway.com/users/1", entries[0].Request.URL)
+	assert.Equal(t, http.StatusOK, entries[0].Response.Status)
+	assert.Equal(t, "upstream response", entries[0].Response.Content.Text)
+	assert.Equal(t, float64(25), entries[0].Timings["_upstream"])
+	assert.Equal(t, map[string]string{"upstreamHost": upstreamURL.Host}, entries[0].Metadata)
+	assert.Empty(t, entries[0].Errors)
+}
+
+func TestSpeakeasy_ReverseProxy_UpstreamError(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeSince(25 * time.Millisecond)
+
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	mu := sync.Mutex{}
+	entries := []reverseProxyEntry{}
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: reverseProxyDialer(t, &mu, &entries),
+	})
+
+	proxy := httputil.NewSingleHostReverseProxy(upstreamURL)
+	proxy.ErrorLog = nil
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://gateway.com/users/1", nil)
+	require.NoError(t, err)
+
+	sdkInstance.ReverseProxy(proxy).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusBadGateway, entries[0].Response.Status)
+	assert.Equal(t, upstreamURL.Host, entries[0].Metadata["upstreamHost"])
+	require.Len(t, entries[0].Errors, 1)
+	assert.Contains(t, entries[0].Errors[0].Message, "connection refused")
+}
+
+func reverseProxyDialer(t *testing.T, mu *sync.Mutex, entries *[]reverseProxyEntry) func() func(context.Context, string) (net.Conn, error) {
+	t.Helper()
+
+	return dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+		var h struct {
+			Log struct {
+				Entries []reverseProxyEntry `json:"entries"`
+			} `json:"log"`
+		}
+
+		err := json.Unmarshal([]byte(req.GetHar()), &h)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		*entries = append(*entries, h.Log.Entries...)
+	})
+}
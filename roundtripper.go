@@ -0,0 +1,287 @@
+package speakeasy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi-validator/paths"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/hooks"
+)
+
+// RoundTripperOption configures the capture of outbound requests made by a RoundTripper.
+type RoundTripperOption func(rt *roundTripper)
+
+// WithRoundTripperMasking will apply the masking options to all outbound requests captured by the RoundTripper.
+func WithRoundTripperMasking(opts ...MaskingOption) RoundTripperOption {
+	return func(rt *roundTripper) {
+		rt.masking = append(rt.masking, opts...)
+	}
+}
+
+// WithUpstreamOpenAPIDocument will resolve the path hints of outbound requests from the OpenAPI document of the upstream API.
+// The RoundTripper will panic if the document can't be parsed.
+func WithUpstreamOpenAPIDocument(document []byte) RoundTripperOption {
+	return func(rt *roundTripper) {
+		doc, err := libopenapi.NewDocument(document)
+		if err != nil {
+			panic(fmt.Errorf("failed to parse OpenAPI document: %w", err))
+		}
+
+		v3Doc, errs := doc.BuildV3Model()
+		if len(errs) > 0 {
+			panic(fmt.Sprintf("failed to build OpenAPI v3 model: %v", errs))
+		}
+
+		rt.doc = v3Doc
+	}
+}
+
+// WithUpstreamAPI will associate the outbound requests captured by the RoundTripper with the Api and Api Version of the
+// upstream API, rather than the ApiID and VersionID of the SDK instance. The RoundTripper will panic if the IDs are invalid.
+func WithUpstreamAPI(apiID, versionID string) RoundTripperOption {
+	return func(rt *roundTripper) {
+		mustValidateIDs(apiID, versionID)
+
+		rt.apiID = apiID
+		rt.versionID = versionID
+	}
+}
+
+type roundTripper struct {
+	sdk       *Speakeasy
+	base      http.RoundTripper
+	masking   []MaskingOption
+	doc       *libopenapi.DocumentModel[v3.Document]
+	apiID     string
+	versionID string
+}
+
+// RoundTripper returns an http.RoundTripper using the default SDK instance to capture the outbound requests made through base,
+// if base is nil http.DefaultTransport is used.
+func RoundTripper(base http.RoundTripper, opts ...RoundTripperOption) http.RoundTripper {
+	return defaultInstance.RoundTripper(base, opts...)
+}
+
+// RoundTripper returns an http.RoundTripper using the current instance of the SDK to capture the outbound requests made through base,
+// if base is nil http.DefaultTransport is used. Response bodies are captured as they are read, so streamed responses are captured
+// once their body has been read to completion or closed. Outbound requests are filtered and sampled as for the Middleware, and their
+// controller is available from the context of the request passed to base.
+func (s *Speakeasy) RoundTripper(base http.RoundTripper, opts ...RoundTripperOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	rt := &roundTripper{
+		sdk:  s,
+		base: base,
+	}
+
+	for _, opt := range opts {
+		opt(rt)
+	}
+
+	return rt
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	startTime := timeNow()
+
+	// Outbound requests are filtered and sampled in the same way as the requests the service receives
+	if !rt.sdk.shouldCapture(req) {
+		return rt.base.RoundTrip(req)
+	}
+
+	// Requests that weren't sampled are still buffered if capture rules could force them to be captured
+	sampled := rt.sdk.isSampled(req, rt.getPathHint)
+	if !sampled && !rt.sdk.hasCaptureRules() {
+		return rt.base.RoundTrip(req)
+	}
+
+	// The controller is provided to the base RoundTripper so it can also be used to skip or force the capture
+	ctx, c := contextWithController(req.Context(), rt.sdk)
+	c.Masking(rt.masking...)
+	c.apiID, c.versionID = rt.apiID, rt.versionID
+
+	conn := &outboundConnection{}
+	c.outbound = conn
+
+	cw := NewCaptureWriter(&outboundResponseWriter{header: http.Header{}}, hooks.MaxCaptureSize)
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn.localAddr = info.Conn.LocalAddr()
+			conn.remoteAddr = info.Conn.RemoteAddr()
+		},
+	}
+	// The request is cloned as a RoundTripper shouldn't modify the request it is given
+	outReq := req.Clone(httptrace.WithClientTrace(ctx, trace))
+	if req.Body != nil && req.Body != http.NoBody {
+		outReq.Body = &teeReadCloser{
+			Reader: io.TeeReader(req.Body, cw.GetRequestWriter()),
+			Closer: req.Body,
+		}
+	}
+
+	capture := func() {
+		cw.finish()
+
+		if c.skipCapture {
+			return
+		}
+
+		if !sampled && !rt.sdk.matchesCaptureRules(cw, startTime, c) {
+			return
+		}
+
+		pathHint := rt.getPathHint(req)
+		if c.pathHint != "" {
+			pathHint = c.pathHint
+		}
+
+		// The capture is built from the original request, with the body already read by the base RoundTripper
+		capReq := req.Clone(req.Context())
+		if req.Body != nil {
+			capReq.Body = http.NoBody
+		}
+
+		rt.sdk.sendCapture(cw, capReq, startTime, pathHint, c)
+	}
+
+	res, err := rt.base.RoundTrip(outReq)
+	if err != nil {
+		// Failed requests are captured with a status of 0 as browsers do
+		c.RecordError(err)
+		cw.status = 0
+		cw.statusWritten = true
+
+		capture()
+
+		return res, err
+	}
+
+	conn.tls = res.TLS
+
+	for key, values := range res.Header {
+		cw.origResW.Header()[key] = values
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		cw.writeHeader(res.StatusCode)
+		capture()
+
+		return res, nil
+	}
+
+	body := &captureReadCloser{
+		body:    res.Body,
+		cw:      cw,
+		capture: capture,
+	}
+	res.Body = body
+
+	// Long-lived event streams are captured once they exceed their limits, as they may never be closed
+	cw.setStreamConfig(streamConfig{
+		startTime:   startTime,
+		maxEvents:   rt.sdk.config.ServerSentEvents.MaxEvents,
+		maxDuration: rt.sdk.config.ServerSentEvents.MaxDuration,
+		onLimit:     body.captureOnce,
+	})
+	cw.writeHeader(res.StatusCode)
+
+	return res, nil
+}
+
+func (rt *roundTripper) getPathHint(req *http.Request) string {
+	if rt.doc != nil {
+		if _, _, pathHint := paths.FindPath(req, &rt.doc.Model); pathHint != "" {
+			return pathHint
+		}
+	}
+
+	// Without the document of the upstream API there is no path hint, as for unmatched routes of the Middleware
+	return ""
+}
+
+// outboundResponseWriter allows the captureWriter to record a response that isn't written to a client, such as a response
+// received by a RoundTripper or the result of a gRPC call.
+type outboundResponseWriter struct {
+	header http.Header
+}
+
+func (w *outboundResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *outboundResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *outboundResponseWriter) WriteHeader(statusCode int) {}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureReadCloser records a response body as it is read, sending the capture once it has been read to completion or closed
+// so streamed responses are passed through as they arrive.
+type captureReadCloser struct {
+	body    io.ReadCloser
+	cw      *captureWriter
+	capture func()
+	once    sync.Once
+	mu      sync.Mutex
+}
+
+func (b *captureReadCloser) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+
+	b.mu.Lock()
+	if n > 0 {
+		//nolint:errcheck
+		b.cw.writeRes(p[:n])
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		b.finish(!errors.Is(err, io.EOF))
+	}
+
+	return n, err
+}
+
+func (b *captureReadCloser) Close() error {
+	err := b.body.Close()
+
+	b.finish(true)
+
+	return err
+}
+
+// finish sends the capture once the body has been read to completion, or marks it as incomplete if it was closed or
+// failed before then.
+func (b *captureReadCloser) finish(incomplete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if incomplete {
+		b.cw.markIncomplete()
+	}
+
+	b.captureOnce()
+}
+
+// captureOnce is also called by the captureWriter once a stream exceeds its limits, at which point the captureWriter has
+// stopped recording so the capture can be sent without the lock held.
+func (b *captureReadCloser) captureOnce() {
+	b.once.Do(func() {
+		b.capture()
+		b.cw.detach()
+	})
+}
@@ -0,0 +1,372 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type outboundCapture struct {
+	PathHint  string
+	ApiID     string
+	VersionID string
+	Entry     struct {
+		Request struct {
+			Method   string `json:"method"`
+			URL      string `json:"url"`
+			BodySize int64  `json:"bodySize"`
+			PostData struct {
+				Text string `json:"text"`
+			} `json:"postData"`
+		} `json:"request"`
+		Response struct {
+			Status  int `json:"status"`
+			Content struct {
+				Size int64  `json:"size"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+		ServerIPAddress string `json:"serverIPAddress"`
+		Outbound        bool   `json:"_outbound"`
+		Incomplete      bool   `json:"_incomplete"`
+		Errors          []struct {
+			Message string `json:"message"`
+		} `json:"_errors"`
+	}
+}
+
+func outboundDialer(t *testing.T, captured chan<- outboundCapture) func() func(context.Context, string) (net.Conn, error) {
+	t.Helper()
+
+	return dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+		var h struct {
+			Log struct {
+				Entries []json.RawMessage `json:"entries"`
+			} `json:"log"`
+		}
+
+		err := json.Unmarshal([]byte(req.GetHar()), &h)
+		require.NoError(t, err)
+
+		c := outboundCapture{PathHint: req.PathHint, ApiID: req.ApiId, VersionID: req.VersionId}
+		err = json.Unmarshal(h.Log.Entries[0], &c.Entry)
+		require.NoError(t, err)
+
+		captured <- c
+	})
+}
+
+func TestSpeakeasy_RoundTripper_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan outboundCapture, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: outboundDialer(t, captured),
+	})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name": "test", "token": "secret"}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1, "token": "secret"}`))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{
+		Transport: sdkInstance.RoundTripper(nil,
+			speakeasy.WithRoundTripperMasking(
+				speakeasy.WithRequestFieldMaskString([]string{"token"}),
+				speakeasy.WithResponseFieldMaskString([]string{"token"}),
+			),
+			speakeasy.WithUpstreamOpenAPIDocument([]byte(`openapi: 3.0.0
+paths:
+  /users/{id}:
+    post:
+      responses:
+        '201':
+          description: Created`)),
+			speakeasy.WithUpstreamAPI("upstream-api", "upstream-version"),
+		),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, upstream.URL+"/users/1?q=1", strings.NewReader(`{"name": "test", "token": "secret"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, `{"id": 1, "token": "secret"}`, string(body))
+
+	c := <-captured
+
+	assert.Equal(t, "/users/{id}", c.PathHint)
+	assert.Equal(t, "upstream-api", c.ApiID)
+	assert.Equal(t, "upstream-version", c.VersionID)
+	assert.True(t, c.Entry.Outbound)
+	assert.False(t, c.Entry.Incomplete)
+	assert.Equal(t, "127.0.0.1", c.Entry.ServerIPAddress)
+	assert.Equal(t, http.MethodPost, c.Entry.Request.Method)
+	assert.Equal(t, upstream.URL+"/users/1?q=1", c.Entry.Request.URL)
+	assert.Equal(t, int64(35), c.Entry.Request.BodySize)
+	assert.Equal(t, `{"name": "test", "token": "__masked__"}`, c.Entry.Request.PostData.Text)
+	assert.Equal(t, http.StatusCreated, c.Entry.Response.Status)
+	assert.Equal(t, int64(28), c.Entry.Response.Content.Size)
+	assert.Equal(t, `{"id": 1, "token": "__masked__"}`, c.Entry.Response.Content.Text)
+}
+
+func TestSpeakeasy_RoundTripper_Streaming_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan outboundCapture, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: outboundDialer(t, captured),
+	})
+
+	next := make(chan struct{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+
+		<-next
+
+		_, _ = w.Write([]byte("second\n"))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: sdkInstance.RoundTripper(nil)}
+
+	res, err := client.Get(upstream.URL + "/stream")
+	require.NoError(t, err)
+
+	// the first chunk should be readable before the upstream has finished the response
+	buf := make([]byte, 6)
+	_, err = io.ReadFull(res.Body, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(buf))
+
+	select {
+	case <-captured:
+		t.Fatal("captured before response was read")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(next)
+
+	rest, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, "second\n", string(rest))
+
+	c := <-captured
+
+	// without the OpenAPI document of the upstream API there is no path hint
+	assert.Equal(t, "", c.PathHint)
+	assert.Equal(t, testApiID, c.ApiID)
+	assert.Equal(t, testVersionID, c.VersionID)
+	assert.False(t, c.Entry.Incomplete)
+	assert.Equal(t, "first\nsecond\n", c.Entry.Response.Content.Text)
+}
+
+func TestSpeakeasy_RoundTripper_ClosedEarly_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan outboundCapture, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: outboundDialer(t, captured),
+	})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: sdkInstance.RoundTripper(nil)}
+
+	res, err := client.Get(upstream.URL + "/stream")
+	require.NoError(t, err)
+
+	buf := make([]byte, 6)
+	_, err = io.ReadFull(res.Body, buf)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	c := <-captured
+
+	assert.True(t, c.Entry.Incomplete)
+	assert.Equal(t, "first\n", c.Entry.Response.Content.Text)
+}
+
+func TestSpeakeasy_RoundTripper_Error(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+
+	captured := make(chan outboundCapture, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:     testAPIKey,
+		ApiID:      testApiID,
+		VersionID:  testVersionID,
+		GRPCDialer: outboundDialer(t, captured),
+	})
+
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	client := &http.Client{Transport: sdkInstance.RoundTripper(nil)}
+
+	res, err := client.Get(upstream.URL + "/users")
+	if res != nil {
+		res.Body.Close()
+	}
+	require.Error(t, err)
+
+	c := <-captured
+
+	assert.Equal(t, 0, c.Entry.Response.Status)
+	require.Len(t, c.Entry.Errors, 1)
+	assert.Contains(t, c.Entry.Errors[0].Message, "connection refused")
+}
+
+func TestSpeakeasy_RoundTripper_NotCaptured(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	tests := []struct {
+		name          string
+		shouldCapture func(r *http.Request) bool
+		sampling      *speakeasy.SamplingConfig
+		skipCapture   bool
+		status        int
+		wantCaptured  bool
+	}{
+		{
+			name: "doesn't capture requests excluded by filters",
+			shouldCapture: func(r *http.Request) bool {
+				return r.URL.Path != "/health"
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:     "doesn't capture requests that weren't sampled",
+			sampling: &speakeasy.SamplingConfig{Rate: 0},
+			status:   http.StatusOK,
+		},
+		{
+			name: "captures requests that weren't sampled matching capture rules",
+			sampling: &speakeasy.SamplingConfig{
+				Rate:         0,
+				CaptureRules: []speakeasy.CaptureRule{{StatusClasses: []int{5}}},
+			},
+			status:       http.StatusInternalServerError,
+			wantCaptured: true,
+		},
+		{
+			name:        "doesn't capture requests skipped by the controller",
+			skipCapture: true,
+			status:      http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+
+			captured := make(chan outboundCapture, 1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:        testAPIKey,
+				ApiID:         testApiID,
+				VersionID:     testVersionID,
+				GRPCDialer:    outboundDialer(t, captured),
+				ShouldCapture: tt.shouldCapture,
+				Sampling:      tt.sampling,
+			})
+
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer upstream.Close()
+
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if tt.skipCapture {
+					ctrl, ok := speakeasy.ControllerFromContext(req.Context())
+					require.True(t, ok)
+
+					ctrl.SkipCapture()
+				}
+
+				return http.DefaultTransport.RoundTrip(req)
+			})
+
+			client := &http.Client{Transport: sdkInstance.RoundTripper(base)}
+
+			res, err := client.Get(upstream.URL + "/health")
+			require.NoError(t, err)
+
+			_, err = io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, tt.status, res.StatusCode)
+
+			select {
+			case <-captured:
+				assert.True(t, tt.wantCaptured, "request shouldn't be captured")
+			default:
+				assert.False(t, tt.wantCaptured, "request should be captured")
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSpeakeasy_RoundTripper_UpstreamAPI_Error(t *testing.T) {
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+	})
+
+	assert.PanicsWithValue(t, speakeasy.ErrVersionIDMissing, func() {
+		sdkInstance.RoundTripper(nil, speakeasy.WithUpstreamAPI("upstream-api", ""))
+	})
+}
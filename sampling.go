@@ -0,0 +1,183 @@
+package speakeasy
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/pathhints"
+)
+
+var randFloat64 = rand.Float64
+
+// SamplingConfig configures head sampling of requests, the decision to capture a request is made before the request is handled
+// so requests that aren't sampled don't incur the cost of buffering their request and response bodies.
+type SamplingConfig struct {
+	// Rate is the fraction of requests captured, between 0 (no requests) and 1 (all requests).
+	Rate float64
+	// Rules override the Rate for requests matching their Method and PathHint, the first matching rule is used.
+	Rules []SamplingRule
+	// CaptureRules force the capture of requests that weren't sampled, they are evaluated once the handler has returned.
+	// If any capture rules are configured requests that aren't sampled are still buffered until the rules have been evaluated.
+	CaptureRules []CaptureRule
+}
+
+// SamplingRule overrides the sampling rate for requests matching a method and path hint.
+type SamplingRule struct {
+	// Method is the HTTP method to match, matches all methods if empty.
+	Method string
+	// PathHint is the normalized path hint to match (for example "/user/{id}"), matches all paths if empty.
+	// Path hints are resolved before the request is handled, so path hints provided by the MiddlewareController
+	// or only available once routing has completed won't be matched.
+	PathHint string
+	// Rate is the fraction of requests captured, between 0 (no requests) and 1 (all requests).
+	Rate float64
+}
+
+// CaptureRule forces the capture of a request that wasn't sampled. A rule matches if all of its conditions match,
+// conditions that aren't set are ignored and a rule without any conditions never matches.
+type CaptureRule struct {
+	// StatusClasses matches responses by the class of their status code, for example 5 matches all 5xx responses.
+	StatusClasses []int
+	// MinLatency matches requests that took at least this long to handle.
+	MinLatency time.Duration
+	// ResponseHeader matches responses containing this header.
+	ResponseHeader string
+	// Forced matches requests where capture has been forced using the ForceCapture method of the MiddlewareController.
+	Forced bool
+}
+
+// traceIDHeaders are the headers checked for a trace ID, allowing all services in a trace to make the same sampling decision.
+var traceIDHeaders = []string{"Traceparent", "X-B3-Traceid", "B3", "X-Datadog-Trace-Id"}
+
+func (s *Speakeasy) isSampled(r *http.Request, capturePathHint func(r *http.Request) string) bool {
+	sampling := s.config.Sampling
+	if sampling == nil {
+		return true
+	}
+
+	rate := sampling.Rate
+
+	pathHint := ""
+	for _, rule := range sampling.Rules {
+		if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+			continue
+		}
+
+		if rule.PathHint != "" {
+			if pathHint == "" {
+				pathHint = pathhints.NormalizePathHint(capturePathHint(r))
+			}
+
+			if rule.PathHint != pathHint {
+				continue
+			}
+		}
+
+		rate = rule.Rate
+		break
+	}
+
+	switch {
+	case rate <= 0:
+		return false
+	case rate >= 1:
+		return true
+	}
+
+	if traceID := getTraceID(r); traceID != "" {
+		return hashTraceID(traceID) < rate
+	}
+
+	return randFloat64() < rate
+}
+
+func (s *Speakeasy) hasCaptureRules() bool {
+	return s.config.Sampling != nil && len(s.config.Sampling.CaptureRules) > 0
+}
+
+func (s *Speakeasy) matchesCaptureRules(cw *captureWriter, startTime time.Time, c *controller) bool {
+	if !s.hasCaptureRules() {
+		return false
+	}
+
+	latency := timeSince(startTime)
+
+	for _, rule := range s.config.Sampling.CaptureRules {
+		if rule.matches(cw, latency, c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r CaptureRule) matches(cw *captureWriter, latency time.Duration, c *controller) bool {
+	if len(r.StatusClasses) == 0 && r.MinLatency <= 0 && r.ResponseHeader == "" && !r.Forced {
+		return false
+	}
+
+	if len(r.StatusClasses) > 0 {
+		matched := false
+		for _, class := range r.StatusClasses {
+			if cw.GetStatus()/100 == class {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if r.MinLatency > 0 && latency < r.MinLatency {
+		return false
+	}
+
+	if r.ResponseHeader != "" && cw.GetResponseHeader().Get(r.ResponseHeader) == "" {
+		return false
+	}
+
+	if r.Forced && !c.forceCapture {
+		return false
+	}
+
+	return true
+}
+
+func getTraceID(r *http.Request) string {
+	for _, header := range traceIDHeaders {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+
+		switch header {
+		case "Traceparent":
+			// version-traceid-parentid-flags
+			parts := strings.Split(value, "-")
+			if len(parts) >= 4 {
+				return parts[1]
+			}
+		case "B3":
+			// traceid-spanid-sampled-parentspanid
+			return strings.Split(value, "-")[0]
+		default:
+			return value
+		}
+	}
+
+	return ""
+}
+
+// hashTraceID maps a trace ID to a value in the range [0, 1).
+func hashTraceID(traceID string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.ToLower(traceID)))
+
+	return float64(h.Sum64()) / (math.MaxUint64 + 1.0)
+}
@@ -0,0 +1,187 @@
+//nolint:testpackage
+package speakeasy
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeakeasy_isSampled(t *testing.T) {
+	defer func() {
+		randFloat64 = rand.Float64
+	}()
+
+	type args struct {
+		method   string
+		pathHint string
+		headers  map[string]string
+		random   float64
+	}
+	tests := []struct {
+		name        string
+		sampling    *SamplingConfig
+		args        args
+		wantSampled bool
+	}{
+		{
+			name:        "samples all requests without sampling config",
+			args:        args{method: http.MethodGet, pathHint: "/user/{id}"},
+			wantSampled: true,
+		},
+		{
+			name:        "samples no requests with zero rate",
+			sampling:    &SamplingConfig{Rate: 0},
+			args:        args{method: http.MethodGet, pathHint: "/user/{id}"},
+			wantSampled: false,
+		},
+		{
+			name:        "samples requests below random rate",
+			sampling:    &SamplingConfig{Rate: 0.5},
+			args:        args{method: http.MethodGet, pathHint: "/user/{id}", random: 0.4},
+			wantSampled: true,
+		},
+		{
+			name:        "doesn't sample requests above random rate",
+			sampling:    &SamplingConfig{Rate: 0.5},
+			args:        args{method: http.MethodGet, pathHint: "/user/{id}", random: 0.6},
+			wantSampled: false,
+		},
+		{
+			name: "rule overrides rate for matching method and path hint",
+			sampling: &SamplingConfig{
+				Rate: 1,
+				Rules: []SamplingRule{
+					{Method: http.MethodGet, PathHint: "/user/{id}", Rate: 0},
+				},
+			},
+			args:        args{method: http.MethodGet, pathHint: "/user/:id"},
+			wantSampled: false,
+		},
+		{
+			name: "rule doesn't override rate for other methods",
+			sampling: &SamplingConfig{
+				Rate: 1,
+				Rules: []SamplingRule{
+					{Method: http.MethodGet, PathHint: "/user/{id}", Rate: 0},
+				},
+			},
+			args:        args{method: http.MethodPost, pathHint: "/user/{id}"},
+			wantSampled: true,
+		},
+		{
+			name: "first matching rule is used",
+			sampling: &SamplingConfig{
+				Rate: 0,
+				Rules: []SamplingRule{
+					{PathHint: "/healthz", Rate: 0},
+					{Method: http.MethodPost, Rate: 1},
+					{Method: http.MethodPost, PathHint: "/user/{id}", Rate: 0},
+				},
+			},
+			args:        args{method: http.MethodPost, pathHint: "/user/{id}"},
+			wantSampled: true,
+		},
+		{
+			name:     "samples using trace id from traceparent",
+			sampling: &SamplingConfig{Rate: 0.5},
+			args: args{
+				method:   http.MethodGet,
+				pathHint: "/user/{id}",
+				headers:  map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+				random:   0.99,
+			},
+			wantSampled: hashTraceID("4bf92f3577b34da6a3ce929d0e0e4736") < 0.5,
+		},
+		{
+			name:     "samples using trace id from b3 header",
+			sampling: &SamplingConfig{Rate: 0.5},
+			args: args{
+				method:   http.MethodGet,
+				pathHint: "/user/{id}",
+				headers:  map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"},
+				random:   0.99,
+			},
+			wantSampled: hashTraceID("80f198ee56343ba864fe8b2a57d3eff7") < 0.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randFloat64 = func() float64 {
+				return tt.args.random
+			}
+
+			s := &Speakeasy{config: Config{Sampling: tt.sampling}}
+
+			r := httptest.NewRequest(tt.args.method, "http://test.com/user/1", nil)
+			for k, v := range tt.args.headers {
+				r.Header.Set(k, v)
+			}
+
+			// the decision should be deterministic for a request
+			for i := 0; i < 3; i++ {
+				assert.Equal(t, tt.wantSampled, s.isSampled(r, func(r *http.Request) string {
+					return tt.args.pathHint
+				}))
+			}
+		})
+	}
+}
+
+func TestHashTraceID_Distribution(t *testing.T) {
+	sampled := 0
+
+	for i := 0; i < 1000; i++ {
+		r := httptest.NewRequest(http.MethodGet, "http://test.com", nil)
+		r.Header.Set("X-B3-TraceId", randomHex(t))
+
+		value := hashTraceID(getTraceID(r))
+		require.GreaterOrEqual(t, value, float64(0))
+		require.Less(t, value, float64(1))
+
+		if value < 0.25 {
+			sampled++
+		}
+	}
+
+	assert.InDelta(t, 250, sampled, 75)
+}
+
+func TestSpeakeasy_Middleware_NotSampled(t *testing.T) {
+	s := &Speakeasy{config: Config{Sampling: &SamplingConfig{Rate: 0}}}
+
+	handled := false
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://test.com/user/1", nil)
+
+	s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(*httptest.ResponseRecorder)
+		assert.True(t, ok, "response writer should not be wrapped")
+
+		ctrl, ok := MiddlewareController(r)
+		require.True(t, ok)
+		ctrl.PathHint("/user/{id}")
+
+		handled = true
+	})).ServeHTTP(w, r)
+
+	assert.True(t, handled)
+}
+
+func randomHex(t *testing.T) string {
+	t.Helper()
+
+	const chars = "0123456789abcdef"
+
+	b := make([]byte, 32)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,113 @@
+//go:build go1.23
+
+//go:debug httpmuxgo121=0
+
+package speakeasy_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeakeasy_ServeMux_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type args struct {
+		pattern string
+		method  string
+		url     string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "removes method from pattern",
+			args: args{
+				pattern: "GET /items/{id}",
+				method:  http.MethodGet,
+				url:     "http://test.com/items/1",
+			},
+			wantPathHint: "/items/{id}",
+		},
+		{
+			name: "removes method and host from pattern",
+			args: args{
+				pattern: "DELETE test.com/items/{id}",
+				method:  http.MethodDelete,
+				url:     "http://test.com/items/1",
+			},
+			wantPathHint: "/items/{id}",
+		},
+		{
+			name: "normalizes remainder wildcard",
+			args: args{
+				pattern: "/files/{path...}",
+				method:  http.MethodGet,
+				url:     "http://test.com/files/a/b/c.txt",
+			},
+			wantPathHint: "/files/{path}",
+		},
+		{
+			name: "normalizes end of path wildcard",
+			args: args{
+				pattern: "GET /{$}",
+				method:  http.MethodGet,
+				url:     "http://test.com/",
+			},
+			wantPathHint: "/",
+		},
+	}
+	for _, tt := range tests {
+		middlewares := map[string]func(sdkInstance *speakeasy.Speakeasy, mux *http.ServeMux, handler http.HandlerFunc) http.Handler{
+			"Middleware": func(sdkInstance *speakeasy.Speakeasy, mux *http.ServeMux, handler http.HandlerFunc) http.Handler {
+				mux.Handle(tt.args.pattern, handler)
+				return sdkInstance.Middleware(mux)
+			},
+			"MiddlewareWithMux": func(sdkInstance *speakeasy.Speakeasy, mux *http.ServeMux, handler http.HandlerFunc) http.Handler {
+				mux.Handle(tt.args.pattern, sdkInstance.MiddlewareWithMux(mux, handler))
+				return mux
+			},
+		}
+
+		for middlewareName, middleware := range middlewares {
+			t.Run(middlewareName+"_"+tt.name, func(t *testing.T) {
+				speakeasy.ExportSetMaxCaptureSize(9437184)
+
+				captured := false
+
+				sdkInstance := speakeasy.New(speakeasy.Config{
+					APIKey:    testAPIKey,
+					ApiID:     testApiID,
+					VersionID: testVersionID,
+					GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+						assert.Equal(t, tt.wantPathHint, req.PathHint)
+						captured = true
+					}),
+				})
+
+				h := middleware(sdkInstance, http.NewServeMux(), func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+
+				w := httptest.NewRecorder()
+
+				req, err := http.NewRequest(tt.args.method, tt.args.url, nil)
+				require.NoError(t, err)
+
+				h.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.True(t, captured)
+			})
+		}
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/pb33f/libopenapi"
 	"github.com/pb33f/libopenapi-validator/paths"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/log"
 	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/embedaccesstoken"
+	"go.uber.org/zap"
 )
 
 var (
@@ -26,6 +28,10 @@ var (
 	ErrVersionIDMissing = errors.New("VersionID is required")
 	// ErrVersionIDMalformed is returned when the Version ID is invalid.
 	ErrVersionIDMalformed = errors.New("VersionID is malformed")
+	// ErrRequestFilterMalformed is returned when a request filter contains an invalid glob pattern or regular expression.
+	ErrRequestFilterMalformed = errors.New("request filter is malformed")
+	// ErrTrustedProxyMalformed is returned when a trusted proxy isn't a valid IP address or CIDR.
+	ErrTrustedProxyMalformed = errors.New("trusted proxy is malformed")
 )
 
 const (
@@ -33,7 +39,7 @@ const (
 )
 
 var (
-	speakeasyVersion = "1.5.0" // TODO get this from CI
+	speakeasyVersion = "1.6.0" // TODO get this from CI
 	serverURL        = "grpc.prod.speakeasyapi.dev:443"
 
 	defaultInstance *Speakeasy
@@ -56,6 +62,42 @@ type Config struct {
 	VersionID       string
 	OpenAPIDocument []byte
 	GRPCDialer      func() func(context.Context, string) (net.Conn, error)
+	// ServerSentEvents configures the capture of text/event-stream responses.
+	ServerSentEvents ServerSentEventsConfig
+	// WebSocket configures the capture of requests upgraded to WebSocket connections.
+	WebSocket WebSocketConfig
+	// Sampling configures the fraction of requests captured, if not provided all requests are captured.
+	Sampling *SamplingConfig
+	// IncludeRequests limits the requests captured to those matching any of the filters, if not provided all requests are captured.
+	IncludeRequests []RequestFilter
+	// ExcludeRequests prevents requests matching any of the filters from being captured, for example health checks or CORS preflights.
+	ExcludeRequests []RequestFilter
+	// ShouldCapture is called for requests that aren't excluded by the request filters, returning false prevents the request being captured.
+	ShouldCapture func(r *http.Request) bool
+	// TrustedProxies are the IP addresses or CIDRs (for example "10.0.0.0/8") of proxies trusted to set the X-Forwarded-*
+	// and Forwarded headers used to resolve the URL and client IP address of a request. If not provided forwarded headers
+	// aren't trusted from any client, []string{"0.0.0.0/0", "::/0"} trusts all clients.
+	TrustedProxies []string
+	// RequestID configures the ID recorded on captured requests to correlate them with logs, if not provided no ID is recorded.
+	// The ID is available to handlers from the MiddlewareController.
+	RequestID *RequestIDConfig
+	// Tags are added to the metadata of every captured request, for example the environment, hostname or build SHA.
+	// Tags with the same key set through the MiddlewareController take precedence.
+	Tags map[string]string
+	// CustomerIDResolver resolves the customer ID of a request once the handler has returned, allowing authentication
+	// middleware to have populated the request. A customer ID provided through the MiddlewareController takes precedence.
+	// See CustomerIDFromHeader, CustomerIDFromContext and CustomerIDFromJWTClaim for built-in resolvers.
+	CustomerIDResolver func(r *http.Request) string
+	// GraphQL enables the capture of GraphQL requests with path hints for their operation, for example "/graphql/query/GetUser",
+	// and their variables recorded separately. If not provided GraphQL requests are captured as any other request.
+	GraphQL *GraphQLConfig
+	// JSONRPC enables the capture of JSON-RPC 2.0 requests with path hints for the method called, for example "/rpc/getUser".
+	// If not provided JSON-RPC requests are captured as any other request.
+	JSONRPC *JSONRPCConfig
+	// PathHintResolvers resolve the path hints of requests for routers that aren't supported by the SDK, they are tried in order
+	// ahead of the ServeMux patterns used by Middleware and MiddlewareWithMux. The speakeasymux, speakeasychi, speakeasyhttprouter
+	// and speakeasybunrouter modules provide resolvers for their routers.
+	PathHintResolvers []PathHintResolver
 }
 
 // Speakeasy is the concrete type for the Speakeasy SDK.
@@ -65,6 +107,9 @@ type Speakeasy struct {
 	harBuilder harBuilder
 	grpcClient *GRPCClient
 	doc        *libopenapi.DocumentModel[v3.Document]
+
+	includeFilters []requestFilter
+	excludeFilters []requestFilter
 }
 
 // Configure allows you to configure the default instance of the Speakeasy SDK.
@@ -99,6 +144,11 @@ func (s *Speakeasy) Close() error {
 	return s.grpcClient.conn.Close()
 }
 
+// Logger returns the logger used by the SDK, allowing the framework integrations of the SDK to log errors in the same way.
+func Logger() *zap.Logger {
+	return log.Logger()
+}
+
 func (s *Speakeasy) MatchOpenAPIPath(r *http.Request) string {
 	if s.doc != nil {
 		_, _, pathHint := paths.FindPath(r, &s.doc.Model)
@@ -132,6 +182,32 @@ func (s *Speakeasy) configure(cfg Config) {
 
 	s.config = cfg
 
+	if s.config.ServerSentEvents.MaxEvents == 0 {
+		s.config.ServerSentEvents.MaxEvents = defaultSSEMaxEvents
+	}
+
+	if s.config.ServerSentEvents.MaxDuration == 0 {
+		s.config.ServerSentEvents.MaxDuration = defaultSSEMaxDuration
+	}
+
+	if s.config.WebSocket.MaxMessages == 0 {
+		s.config.WebSocket.MaxMessages = defaultWebSocketMaxMessages
+	}
+
+	if s.config.GraphQL != nil && len(s.config.GraphQL.Paths) == 0 {
+		graphQL := *s.config.GraphQL
+		graphQL.Paths = []string{defaultGraphQLPath}
+		s.config.GraphQL = &graphQL
+	}
+
+	s.harBuilder = harBuilder{
+		trustedProxies: mustParseTrustedProxies(s.config.TrustedProxies),
+		tags:           s.config.Tags,
+	}
+
+	s.includeFilters = mustCompileFilters(s.config.IncludeRequests)
+	s.excludeFilters = mustCompileFilters(s.config.ExcludeRequests)
+
 	grpcClient, err := newGRPCClient(context.Background(), s.config.APIKey, configuredServerURL, secure, s.config.GRPCDialer)
 	s.grpcClient = grpcClient
 	if err != nil {
@@ -158,27 +234,31 @@ func mustValidateConfig(cfg Config) {
 		panic(ErrAPIKeyMissing)
 	}
 
-	if cfg.ApiID == "" {
+	mustValidateIDs(cfg.ApiID, cfg.VersionID)
+}
+
+func mustValidateIDs(apiID, versionID string) {
+	if apiID == "" {
 		panic(ErrApiIDMissing)
 	}
 
-	if len(cfg.ApiID) > maxIDSize {
+	if len(apiID) > maxIDSize {
 		panic(fmt.Errorf("ApiID is too long. Max length is %d: %w", maxIDSize, ErrApiIDMalformed))
 	}
 
-	if validCharsRegex.MatchString(cfg.ApiID) {
+	if validCharsRegex.MatchString(apiID) {
 		panic(fmt.Errorf("ApiID contains invalid characters %s: %w", validCharsRegexStr, ErrApiIDMalformed))
 	}
 
-	if cfg.VersionID == "" {
+	if versionID == "" {
 		panic(ErrVersionIDMissing)
 	}
 
-	if len(cfg.VersionID) > maxIDSize {
+	if len(versionID) > maxIDSize {
 		panic(fmt.Errorf("VersionID is too long. Max length is %d: %w", maxIDSize, ErrVersionIDMalformed))
 	}
 
-	if validCharsRegex.MatchString(cfg.VersionID) {
+	if validCharsRegex.MatchString(versionID) {
 		panic(fmt.Errorf("VersionID contains invalid characters %s: %w", validCharsRegexStr, ErrVersionIDMalformed))
 	}
 }
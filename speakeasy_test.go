@@ -87,7 +87,6 @@ func TestConfigure_Error(t *testing.T) {
 	}
 	tests := []struct {
 		name    string
-		fields  fields
 		args    args
 		wantErr string
 	}{
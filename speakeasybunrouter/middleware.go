@@ -0,0 +1,38 @@
+// Package speakeasybunrouter captures requests to uptrace/bunrouter routers with the Speakeasy SDK.
+package speakeasybunrouter
+
+import (
+	"net/http"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/uptrace/bunrouter"
+)
+
+// PathHintResolver resolves the path hint of a request from the route matched by bunrouter, for example "/v1/users/:id".
+// Handlers must be registered with bunrouter.HTTPHandler or bunrouter.HTTPHandlerFunc for the route to be available to the SDK.
+var PathHintResolver speakeasy.PathHintResolver = speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+	return bunrouter.ParamsFromContext(r.Context()).Route()
+})
+
+// Middleware setups up the default SDK instance to start capturing requests from bunrouter routers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = speakeasy.Capture(w, r, handler(next), PathHintResolver)
+	})
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from bunrouter routers.
+func New(sdkInstance *speakeasy.Speakeasy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = sdkInstance.Capture(w, r, handler(next), PathHintResolver)
+		})
+	}
+}
+
+func handler(next http.Handler) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		next.ServeHTTP(w, r)
+		return nil, nil
+	}
+}
@@ -0,0 +1,42 @@
+// Package speakeasychi captures requests to go-chi/chi routers with the Speakeasy SDK.
+package speakeasychi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+)
+
+// PathHintResolver resolves the path hint of a request from the route pattern matched by chi, for example "/v1/users/{id}".
+var PathHintResolver speakeasy.PathHintResolver = speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil {
+		return ""
+	}
+
+	return routeContext.RoutePattern()
+})
+
+// Middleware setups up the default SDK instance to start capturing requests from chi routers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = speakeasy.Capture(w, r, handler(next), PathHintResolver)
+	})
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from chi routers.
+func New(sdkInstance *speakeasy.Speakeasy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = sdkInstance.Capture(w, r, handler(next), PathHintResolver)
+		})
+	}
+}
+
+func handler(next http.Handler) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		next.ServeHTTP(w, r)
+		return nil, nil
+	}
+}
@@ -0,0 +1,123 @@
+package speakeasychi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasychi"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	captured := false
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			assert.Equal(t, "/v1/users/{id}/files/{wildcard}", req.PathHint)
+			captured = true
+		}),
+	})
+
+	r := chi.NewRouter()
+	r.Use(speakeasychi.New(sdkInstance))
+	r.Route("/v1/users/{id}", func(r chi.Router) {
+		r.Get("/files/*", func(w http.ResponseWriter, req *http.Request) {
+			_, ok := speakeasy.MiddlewareController(req)
+			assert.True(t, ok)
+
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1/files/a.txt", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, captured)
+}
+
+func TestNew_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type args struct {
+		path string
+		url  string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from chi",
+			args: args{
+				path: "/user/{id}",
+				url:  "http://test.com/user/1",
+			},
+			wantPathHint: "/user/{id}",
+		},
+		{
+			name: "captures complex path hint from chi",
+			args: args{
+				path: "/user/{id}/account/*",
+				url:  "http://test.com/user/abcdefg/account/1",
+			},
+			wantPathHint: "/user/{id}/account/{wildcard}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+				}),
+			})
+
+			r := chi.NewRouter()
+			r.Use(speakeasychi.New(sdkInstance))
+			r.Get(tt.args.path, func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Package speakeasyecho captures requests to the echo http framework with the Speakeasy SDK.
+package speakeasyecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+)
+
+// Middleware setups up the default SDK instance to start capturing requests from the echo http framework.
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return speakeasy.Capture(c.Response(), c.Request(), handler(c, next), pathHintResolver(c))
+	}
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from the echo http framework.
+func New(sdkInstance *speakeasy.Speakeasy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return sdkInstance.Capture(c.Response(), c.Request(), handler(c, next), pathHintResolver(c))
+		}
+	}
+}
+
+func handler(c echo.Context, next echo.HandlerFunc) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		c.SetResponse(echo.NewResponse(w, c.Echo()))
+		c.SetRequest(r)
+
+		// Echo's error handler writes the response for an error once the handler chain has returned, so it is invoked here
+		// (as echo's own middleware does) to allow the response it writes to be captured. The error is still returned, as
+		// echo's error handler skips responses that have already been committed.
+		err := next(c)
+		if err != nil {
+			if ctrl, ok := speakeasy.MiddlewareController(r); ok {
+				ctrl.RecordError(err)
+			}
+
+			c.Error(err)
+		}
+
+		return c.Request(), err
+	}
+}
+
+// pathHintResolver resolves the path hint of the request from the route matched by echo, for example "/v1/users/:id".
+func pathHintResolver(c echo.Context) speakeasy.PathHintResolver {
+	return speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+		return c.Path()
+	})
+}
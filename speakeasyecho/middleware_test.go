@@ -0,0 +1,245 @@
+package speakeasyecho_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasyecho"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	captured := false
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []struct {
+						Response struct {
+							Status int `json:"status"`
+						} `json:"response"`
+						Errors []struct {
+							Message string `json:"message"`
+						} `json:"_errors"`
+					} `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			entry := h.Log.Entries[0]
+
+			assert.Equal(t, "/v1/users/{id}", req.PathHint)
+			assert.Equal(t, http.StatusNotFound, entry.Response.Status)
+			require.Len(t, entry.Errors, 1)
+			assert.Equal(t, "code=404, message=user not found", entry.Errors[0].Message)
+
+			captured = true
+		}),
+	})
+
+	var returnedErr error
+
+	e := echo.New()
+	// the error should be returned to the middleware before the speakeasy middleware
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			returnedErr = next(c)
+			return returnedErr
+		}
+	})
+	e.Use(speakeasyecho.New(sdkInstance))
+	e.GET("/v1/users/:id", func(c echo.Context) error {
+		_, ok := speakeasy.MiddlewareController(c.Request())
+		assert.True(t, ok)
+
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1", nil)
+	require.NoError(t, err)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"message": "user not found"}`, w.Body.String())
+	assert.True(t, captured)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, returnedErr, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestNew_Capture_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	tests := speakeasytest.LoadGoldenTests(t, "../testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tt.Setup(t)
+
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.JSONEq(t, tt.WantHAR, req.Har)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			e := echo.New()
+			e.Use(speakeasyecho.New(sdkInstance))
+			e.Any("/*", func(c echo.Context) error {
+				ctrl, _ := speakeasy.MiddlewareController(c.Request())
+				ctrl.Masking(tt.MaskingOptions()...)
+
+				for _, header := range tt.Args.ResponseHeaders {
+					for _, val := range header.Values {
+						c.Response().Header().Add(header.Key, val)
+					}
+				}
+
+				if c.Request().Body != nil {
+					data, err := io.ReadAll(c.Request().Body)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.Args.Body, string(data))
+				}
+
+				if tt.Args.ResponseStatus > 0 {
+					c.Response().WriteHeader(tt.Args.ResponseStatus)
+				}
+
+				if tt.Args.ResponseBody != "" {
+					_, err := c.Response().Write([]byte(tt.Args.ResponseBody))
+					assert.NoError(t, err)
+				}
+				handled = true
+
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+
+			e.ServeHTTP(w, tt.NewRequest(t))
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, tt.WantResponseStatus(), w.Code)
+		})
+	}
+}
+
+func TestNew_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from echo",
+			args: args{
+				path: "/user",
+				url:  "http://test.com/user",
+			},
+			wantPathHint: "/user",
+		},
+		{
+			name: "captures more complex path hint from echo",
+			args: args{
+				path: "/user/:id/*",
+				url:  "http://test.com/user/1/send",
+			},
+			wantPathHint: "/user/{id}/{wildcard}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/:id/*action",
+				url:     "http://test.com/user/1/sent",
+				devHint: "/user/{id}/{action}",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+				}),
+			})
+
+			e := echo.New()
+			e.Use(speakeasyecho.New(sdkInstance))
+			e.Match([]string{http.MethodGet}, tt.args.path, func(c echo.Context) error {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(c.Request())
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			require.NoError(t, err)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package speakeasyfiber captures requests to the fiber http framework with the Speakeasy SDK.
+package speakeasyfiber
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"go.uber.org/zap"
+)
+
+// Middleware setups up the default SDK instance to start capturing requests from the fiber http framework.
+// The speakeasy controller is available to handlers through speakeasy.ControllerFromContext(c.UserContext()).
+// Responses are captured once the handler chain has returned, the bodies of streamed responses aren't captured.
+func Middleware(c *fiber.Ctx) error {
+	r, err := newRequest(c)
+	if err != nil {
+		speakeasy.Logger().Error("speakeasy-sdk: failed to convert fiber request", zap.Error(err))
+		return c.Next()
+	}
+
+	return speakeasy.Capture(&responseWriter{header: http.Header{}}, r, next(c), pathHintResolver(c))
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from the fiber http framework.
+// The speakeasy controller is available to handlers through speakeasy.ControllerFromContext(c.UserContext()).
+// Responses are captured once the handler chain has returned, the bodies of streamed responses aren't captured.
+func New(sdkInstance *speakeasy.Speakeasy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		r, err := newRequest(c)
+		if err != nil {
+			speakeasy.Logger().Error("speakeasy-sdk: failed to convert fiber request", zap.Error(err))
+			return c.Next()
+		}
+
+		return sdkInstance.Capture(&responseWriter{header: http.Header{}}, r, next(c), pathHintResolver(c))
+	}
+}
+
+func next(c *fiber.Ctx) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		ctrl, _ := speakeasy.MiddlewareController(r)
+
+		// Fiber has already read the request body so it is read before the handler is called, if the request is recorded
+		if r.Body != nil && ctrl.Recording() {
+			//nolint:errcheck
+			io.Copy(io.Discard, r.Body)
+		}
+
+		c.SetUserContext(r.Context())
+
+		// Fiber's error handler writes the response for an error once the handler chain has returned, so it is invoked here
+		// (as for echo) to allow the response it writes to be captured
+		if err := c.Next(); err != nil {
+			ctrl.RecordError(err)
+
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		if ctrl.Recording() {
+			writeResponse(w, c.Response())
+		}
+
+		return r.WithContext(c.UserContext()), nil
+	}
+}
+
+// pathHintResolver resolves the path hint of the request from the route matched by fiber, for example "/v1/users/:id".
+func pathHintResolver(c *fiber.Ctx) speakeasy.PathHintResolver {
+	return speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+		return c.Route().Path
+	})
+}
+
+// newRequest converts the fasthttp request of a fiber context to an http.Request. Its values are copied as fasthttp
+// reuses the request once the handler has returned, which can be before the request is captured, except for its body
+// which is only read (and so recorded) while the handler is called if the request is recorded.
+func newRequest(c *fiber.Ctx) (*http.Request, error) {
+	req := c.Request()
+
+	// The request URI is taken as it was received, as fasthttp resolves an absolute request URI against the Host header
+	requestURI := string(req.Header.RequestURI())
+
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, err
+	}
+
+	// As for net/http the host of an absolute request URI takes precedence over the Host header
+	host := u.Host
+	if host == "" {
+		host = string(req.Host())
+	}
+
+	r := &http.Request{
+		Method:     string(req.Header.Method()),
+		URL:        u,
+		Proto:      string(req.Header.Protocol()),
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       host,
+		RequestURI: requestURI,
+		TLS:        c.Context().TLSConnectionState(),
+	}
+
+	if body := req.Body(); len(body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+
+	// fasthttp reports the unspecified address if the request wasn't received on a connection
+	if addr := c.Context().RemoteAddr().String(); addr != "0.0.0.0:0" {
+		r.RemoteAddr = addr
+	}
+
+	req.Header.VisitAll(func(key, value []byte) {
+		switch k := string(key); k {
+		case "Transfer-Encoding":
+			r.TransferEncoding = append(r.TransferEncoding, string(value))
+		default:
+			r.Header.Add(k, string(value))
+		}
+	})
+
+	return r.WithContext(c.UserContext()), nil
+}
+
+// writeResponse writes the response of a fiber handler so it can be captured.
+func writeResponse(w http.ResponseWriter, res *fiber.Response) {
+	res.Header.VisitAll(func(key, value []byte) {
+		w.Header().Add(string(key), string(value))
+	})
+
+	w.WriteHeader(res.StatusCode())
+
+	// Reading a streamed body would consume it before it is sent, and may never complete for long-lived streams
+	if res.IsBodyStream() {
+		return
+	}
+
+	//nolint:errcheck
+	w.Write(res.Body())
+}
+
+// responseWriter allows the SDK to capture the response of a fiber handler, which fiber sends to the client itself.
+type responseWriter struct {
+	header http.Header
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {}
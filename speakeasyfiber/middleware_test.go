@@ -0,0 +1,229 @@
+package speakeasyfiber_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasyfiber"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	type entry struct {
+		Response struct {
+			Status  int `json:"status"`
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+		Errors []struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"_errors"`
+	}
+
+	type capture struct {
+		pathHint   string
+		customerID string
+		entry      entry
+	}
+
+	captured := make(chan capture, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []entry `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			captured <- capture{pathHint: req.PathHint, customerID: req.CustomerId, entry: h.Log.Entries[0]}
+		}),
+	})
+
+	app := fiber.New()
+	app.Use(speakeasyfiber.New(sdkInstance))
+	app.Get("/v1/users/:id", func(c *fiber.Ctx) error {
+		ctrl, ok := speakeasy.ControllerFromContext(c.UserContext())
+		require.True(t, ok)
+		ctrl.CustomerID("customer-" + c.Params("id"))
+
+		return fiber.NewError(http.StatusNotFound, "user not found")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1", nil)
+	require.NoError(t, err)
+
+	// The request is sent through fasthttp, so the capture is sent after fasthttp has reused the request
+	res, err := app.Test(req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "user not found", string(body))
+
+	c := <-captured
+
+	assert.Equal(t, "/v1/users/{id}", c.pathHint)
+	assert.Equal(t, "customer-1", c.customerID)
+	assert.Equal(t, http.StatusNotFound, c.entry.Response.Status)
+	assert.Equal(t, "user not found", c.entry.Response.Content.Text)
+	require.Len(t, c.entry.Errors, 1)
+	assert.Equal(t, "user not found", c.entry.Errors[0].Message)
+	assert.Equal(t, "*fiber.Error", c.entry.Errors[0].Type)
+}
+
+func TestNew_Capture_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	tests := speakeasytest.LoadGoldenTests(t, "../testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tt.Setup(t)
+
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.JSONEq(t, tt.WantHAR, req.Har)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			app := fiber.New()
+			app.Use(speakeasyfiber.New(sdkInstance))
+			app.All("/*", func(c *fiber.Ctx) error {
+				ctrl, _ := speakeasy.ControllerFromContext(c.UserContext())
+				ctrl.Masking(tt.MaskingOptions()...)
+
+				for _, header := range tt.Args.ResponseHeaders {
+					for _, val := range header.Values {
+						c.Response().Header.Add(header.Key, val)
+					}
+				}
+
+				assert.Equal(t, tt.Args.Body, string(c.Body()))
+
+				if tt.Args.ResponseStatus > 0 {
+					c.Status(tt.Args.ResponseStatus)
+				}
+
+				if tt.Args.ResponseBody != "" {
+					_, err := c.Write([]byte(tt.Args.ResponseBody))
+					assert.NoError(t, err)
+				}
+				handled = true
+
+				return nil
+			})
+
+			// The request is handled directly by fasthttp so it is received as constructed, as with httptest
+			reqCtx := &fasthttp.RequestCtx{}
+			// as for a server configured with DisableDefaultContentType, as the test handlers don't set a Content-Type
+			reqCtx.Response.Header.SetNoDefaultContentType(true)
+			reqCtx.Request.Header.SetMethod(tt.Args.Method)
+			reqCtx.Request.SetRequestURI(tt.Args.URL)
+			if tt.Args.Body != "" {
+				reqCtx.Request.SetBodyString(tt.Args.Body)
+			}
+
+			for _, header := range tt.Args.Headers {
+				for _, val := range header.Values {
+					reqCtx.Request.Header.Add(header.Key, val)
+				}
+			}
+
+			app.Handler()(reqCtx)
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, tt.WantResponseStatus(), reqCtx.Response.StatusCode())
+		})
+	}
+}
+
+func TestNew_NotCaptured_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	captured := make(chan struct{}, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			captured <- struct{}{}
+		}),
+		ShouldCapture: func(r *http.Request) bool {
+			return r.Header.Get("X-Capture") != "false"
+		},
+	})
+
+	app := fiber.New()
+	app.Use(speakeasyfiber.New(sdkInstance))
+	app.Post("/v1/users", func(c *fiber.Ctx) error {
+		ctrl, ok := speakeasy.ControllerFromContext(c.UserContext())
+		require.True(t, ok)
+		assert.False(t, ctrl.Recording())
+
+		return c.Status(http.StatusCreated).Send(c.Body())
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "http://test.com/v1/users", strings.NewReader(`{"name": "test"}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Capture", "false")
+
+	res, err := app.Test(req)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, `{"name": "test"}`, string(body))
+
+	select {
+	case <-captured:
+		t.Error("request was captured")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
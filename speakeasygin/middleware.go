@@ -0,0 +1,81 @@
+// Package speakeasygin captures requests to the gin http framework with the Speakeasy SDK.
+package speakeasygin
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+)
+
+// Middleware setups up the default SDK instance to start capturing requests from the gin http framework.
+func Middleware(c *gin.Context) {
+	_ = speakeasy.Capture(c.Writer, c.Request, next(c), pathHintResolver(c))
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from the gin http framework.
+func New(sdkInstance *speakeasy.Speakeasy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_ = sdkInstance.Capture(c.Writer, c.Request, next(c), pathHintResolver(c))
+	}
+}
+
+func next(c *gin.Context) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		c.Writer = &responseWriter{c.Writer, w}
+		c.Request = r
+
+		c.Next()
+
+		// Errors collected by gin aren't returned, so they are recorded here to be attached to the capture
+		if ctrl, ok := speakeasy.MiddlewareController(r); ok {
+			for _, err := range c.Errors {
+				ctrl.RecordError(err.Err)
+			}
+		}
+
+		return c.Request, nil
+	}
+}
+
+// pathHintResolver resolves the path hint of the request from the route matched by gin, for example "/v1/users/:id".
+func pathHintResolver(c *gin.Context) speakeasy.PathHintResolver {
+	return speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+		return c.FullPath()
+	})
+}
+
+type responseWriter struct {
+	gin.ResponseWriter
+	writer http.ResponseWriter
+}
+
+var _ gin.ResponseWriter = &responseWriter{}
+
+func (g *responseWriter) Write(data []byte) (int, error) {
+	return g.writer.Write(data)
+}
+
+func (g *responseWriter) WriteString(s string) (int, error) {
+	return g.writer.Write([]byte(s))
+}
+
+func (g *responseWriter) WriteHeader(statusCode int) {
+	g.writer.WriteHeader(statusCode)
+}
+
+func (g *responseWriter) Flush() {
+	if f, ok := g.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := g.writer.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return g.ResponseWriter.Hijack()
+}
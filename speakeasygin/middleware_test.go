@@ -0,0 +1,334 @@
+package speakeasygin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasygin"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	captured := false
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []struct {
+						Errors []struct {
+							Message string `json:"message"`
+						} `json:"_errors"`
+					} `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			assert.Equal(t, "/v1/users/{id}", req.PathHint)
+			require.Len(t, h.Log.Entries[0].Errors, 1)
+			assert.Equal(t, "user not found", h.Log.Entries[0].Errors[0].Message)
+
+			captured = true
+		}),
+	})
+
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(speakeasygin.New(sdkInstance))
+	r.GET("/v1/users/:id", func(c *gin.Context) {
+		_, ok := speakeasy.MiddlewareController(c.Request)
+		assert.True(t, ok)
+
+		_ = c.AbortWithError(http.StatusNotFound, errors.New("user not found"))
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, captured)
+}
+
+func TestNew_Capture_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+
+	tests := speakeasytest.LoadGoldenTests(t, "../testdata")
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tt.Setup(t)
+
+			captured := false
+			handled := false
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.JSONEq(t, tt.WantHAR, req.Har)
+					captured = true
+					wg.Done()
+				}),
+			})
+
+			gin.SetMode(gin.TestMode)
+
+			r := gin.New()
+			r.Use(speakeasygin.New(sdkInstance))
+			r.Any("/*path", func(c *gin.Context) {
+				ctrl, _ := speakeasy.MiddlewareController(c.Request)
+				ctrl.Masking(tt.MaskingOptions()...)
+
+				for _, header := range tt.Args.ResponseHeaders {
+					for _, val := range header.Values {
+						c.Writer.Header().Add(header.Key, val)
+					}
+				}
+
+				if c.Request.Body != nil {
+					data, err := io.ReadAll(c.Request.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.Args.Body, string(data))
+				}
+
+				if tt.Args.ResponseStatus > 0 {
+					c.Writer.WriteHeader(tt.Args.ResponseStatus)
+				}
+
+				if tt.Args.ResponseBody != "" {
+					_, err := c.Writer.Write([]byte(tt.Args.ResponseBody))
+					assert.NoError(t, err)
+				}
+				handled = true
+			})
+
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, tt.NewRequest(t))
+
+			wg.Wait()
+
+			assert.True(t, handled, "middleware did not call handler")
+			assert.True(t, captured, "middleware did not capture request")
+			assert.Equal(t, tt.WantResponseStatus(), w.Code)
+		})
+	}
+}
+
+func TestNew_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from gin",
+			args: args{
+				path: "/user",
+				url:  "http://test.com/user",
+			},
+			wantPathHint: "/user",
+		},
+		{
+			name: "captures more complex path hint from gin",
+			args: args{
+				path: "/user/:id/*action",
+				url:  "http://test.com/user/1/send",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/:id/*action",
+				url:     "http://test.com/user/1/sent",
+				devHint: "/user/{id}/{action}",
+			},
+			wantPathHint: "/user/{id}/{action}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+				}),
+			})
+
+			gin.SetMode(gin.TestMode)
+
+			r := gin.New()
+			r.Use(speakeasygin.New(sdkInstance))
+			r.Handle(http.MethodGet, tt.args.path, func(c *gin.Context) {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(c.Request)
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+				c.Writer.WriteHeader(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
+		})
+	}
+}
+
+func TestNew_HandlerErrors_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type entry struct {
+		Response struct {
+			Status  int `json:"status"`
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"response"`
+		Errors []struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"_errors"`
+	}
+
+	var captured entry
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []entry `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			captured = h.Log.Entries[0]
+		}),
+	})
+
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(speakeasygin.New(sdkInstance))
+	r.GET("/test", func(c *gin.Context) {
+		_ = c.Error(io.ErrUnexpectedEOF)
+		_ = c.Error(&json.SyntaxError{})
+		c.String(http.StatusBadRequest, "invalid request")
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, http.StatusBadRequest, captured.Response.Status)
+	assert.Equal(t, "invalid request", captured.Response.Content.Text)
+	require.Len(t, captured.Errors, 2)
+	assert.Equal(t, "unexpected EOF", captured.Errors[0].Message)
+	assert.Equal(t, "*errors.errorString", captured.Errors[0].Type)
+	assert.Equal(t, "*json.SyntaxError", captured.Errors[1].Type)
+}
+
+func TestNew_CustomerIDResolver_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type contextKey struct{}
+
+	var capturedCustomerID string
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			capturedCustomerID = req.CustomerId
+		}),
+		CustomerIDResolver: speakeasy.CustomerIDFromContext(contextKey{}),
+	})
+
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(speakeasygin.New(sdkInstance))
+	// authentication middleware running after the speakeasy middleware
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, "an-authenticated-customer"))
+		c.Next()
+	})
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/test", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "an-authenticated-customer", capturedCustomerID)
+}
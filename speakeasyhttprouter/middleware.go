@@ -0,0 +1,38 @@
+// Package speakeasyhttprouter captures requests to julienschmidt/httprouter routers with the Speakeasy SDK.
+package speakeasyhttprouter
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+)
+
+// PathHintResolver resolves the path hint of a request from the route path matched by httprouter, for example "/v1/users/:id".
+// The router must have SaveMatchedRoutePath enabled for the matched route path to be available to the SDK.
+var PathHintResolver speakeasy.PathHintResolver = speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+	return httprouter.ParamsFromContext(r.Context()).MatchedRoutePath()
+})
+
+// Middleware setups up the default SDK instance to start capturing requests from httprouter routers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = speakeasy.Capture(w, r, handler(next), PathHintResolver)
+	})
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from httprouter routers.
+func New(sdkInstance *speakeasy.Speakeasy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = sdkInstance.Capture(w, r, handler(next), PathHintResolver)
+		})
+	}
+}
+
+func handler(next http.Handler) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		next.ServeHTTP(w, r)
+		return nil, nil
+	}
+}
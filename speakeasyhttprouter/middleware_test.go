@@ -0,0 +1,80 @@
+package speakeasyhttprouter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasyhttprouter"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	tests := []struct {
+		name       string
+		middleware func(sdkInstance *speakeasy.Speakeasy, handler http.Handler) http.Handler
+	}{
+		{
+			name: "resolves path hint with middleware",
+			middleware: func(sdkInstance *speakeasy.Speakeasy, handler http.Handler) http.Handler {
+				return speakeasyhttprouter.New(sdkInstance)(handler)
+			},
+		},
+		{
+			name: "resolves path hint with resolver provided in config",
+			middleware: func(sdkInstance *speakeasy.Speakeasy, handler http.Handler) http.Handler {
+				return sdkInstance.Middleware(handler)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, "/v1/users/{id}/files/{filepath}", req.PathHint)
+					captured = true
+				}),
+				PathHintResolvers: []speakeasy.PathHintResolver{speakeasyhttprouter.PathHintResolver},
+			})
+
+			r := httprouter.New()
+			r.SaveMatchedRoutePath = true
+			r.Handler(http.MethodGet, "/v1/users/:id/files/*filepath", tt.middleware(sdkInstance, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, ok := speakeasy.MiddlewareController(req)
+				assert.True(t, ok)
+
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1/files/a/b.txt", nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package speakeasymux captures requests to gorilla/mux routers with the Speakeasy SDK.
+package speakeasymux
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+)
+
+// PathHintResolver resolves the path hint of a request from the path template of the route matched by gorilla/mux,
+// for example "/v1/users/{id}".
+var PathHintResolver speakeasy.PathHintResolver = speakeasy.PathHintResolverFunc(func(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	pathHint, _ := route.GetPathTemplate()
+
+	return pathHint
+})
+
+// Middleware setups up the default SDK instance to start capturing requests from gorilla/mux routers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = speakeasy.Capture(w, r, handler(next), PathHintResolver)
+	})
+}
+
+// New returns a middleware using the provided instance of the SDK to start capturing requests from gorilla/mux routers.
+func New(sdkInstance *speakeasy.Speakeasy) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = sdkInstance.Capture(w, r, handler(next), PathHintResolver)
+		})
+	}
+}
+
+func handler(next http.Handler) func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+		next.ServeHTTP(w, r)
+		return nil, nil
+	}
+}
@@ -0,0 +1,137 @@
+package speakeasymux_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasymux"
+	"github.com/speakeasy-api/speakeasy-go-sdk/speakeasytest"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAPIKey    = "test"
+	testApiID     = "testApiID"
+	testVersionID = "testVersionID"
+)
+
+func TestNew_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	captured := false
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			assert.Equal(t, "/v1/users/{id}/files/{name}", req.PathHint)
+			captured = true
+		}),
+	})
+
+	r := mux.NewRouter()
+	r.Use(speakeasymux.New(sdkInstance))
+	r.HandleFunc("/v1/users/{id}/files/{name:[a-z.]+}", func(w http.ResponseWriter, req *http.Request) {
+		_, ok := speakeasy.MiddlewareController(req)
+		assert.True(t, ok)
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/v1/users/1/files/a.txt", nil)
+	require.NoError(t, err)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, captured)
+}
+
+func TestNew_PathHint_Success(t *testing.T) {
+	t.Setenv("SPEAKEASY_SERVER_SECURE", "false")
+	t.Setenv("SPEAKEASY_SDK_CAPTURE_INLINE", "true")
+
+	type args struct {
+		path    string
+		url     string
+		devHint string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantPathHint string
+	}{
+		{
+			name: "captures simple path hint from mux",
+			args: args{
+				path: "/user/{id}",
+				url:  "http://test.com/user/1",
+			},
+			wantPathHint: "/user/{id}",
+		},
+		{
+			name: "captures more complex path hint from mux",
+			args: args{
+				path: "/user/{id:[0-9]+}/account/{accountID}",
+				url:  "http://test.com/user/1/account/abcdefg",
+			},
+			wantPathHint: "/user/{id}/account/{accountID}",
+		},
+		{
+			name: "path hint is overridden by dev hint",
+			args: args{
+				path:    "/user/{id:[0-9]+}/account/{accountID}",
+				url:     "http://test.com/user/1/account/abcdefg",
+				devHint: "/user/{id}/account/{accountID}",
+			},
+			wantPathHint: "/user/{id}/account/{accountID}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured := false
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: speakeasytest.Dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, tt.wantPathHint, req.PathHint)
+					captured = true
+				}),
+			})
+
+			r := mux.NewRouter()
+			r.Use(speakeasymux.New(sdkInstance))
+			r.Methods(http.MethodGet).Path(tt.args.path).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tt.args.devHint != "" {
+					ctrl, _ := speakeasy.MiddlewareController(req)
+					require.NotNil(t, ctrl)
+					ctrl.PathHint(tt.args.devHint)
+				}
+
+				w.WriteHeader(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, tt.args.url, nil)
+			require.NoError(t, err)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, captured)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package speakeasytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-go-sdk/internal/hooks"
+	"github.com/stretchr/testify/require"
+)
+
+// Header is a header of the request or response of a golden test.
+type Header struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// Fields configures the SDK for a golden test.
+type Fields struct {
+	MaxCaptureSize int `json:"max_capture_size,omitempty"`
+}
+
+// Args describes the request sent by a golden test and the response written by its handler.
+type Args struct {
+	Method                   string            `json:"method"`
+	URL                      string            `json:"url"`
+	Headers                  []Header          `json:"headers"`
+	Body                     string            `json:"body"`
+	RequestStartTime         time.Time         `json:"request_start_time"`
+	ElapsedTime              int               `json:"elapsed_time"`
+	ResponseStatus           int               `json:"response_status"`
+	ResponseBody             string            `json:"response_body"`
+	ResponseHeaders          []Header          `json:"response_headers"`
+	QueryStringMasks         map[string]string `json:"query_string_masks"`
+	RequestHeaderMasks       map[string]string `json:"request_header_masks"`
+	RequestCookieMasks       map[string]string `json:"request_cookie_masks"`
+	RequestFieldMasksString  map[string]string `json:"request_field_masks_string"`
+	RequestFieldMasksNumber  map[string]string `json:"request_field_masks_number"`
+	ResponseHeaderMasks      map[string]string `json:"response_header_masks"`
+	ResponseCookieMasks      map[string]string `json:"response_cookie_masks"`
+	ResponseFieldMasksString map[string]string `json:"response_field_masks_string"`
+	ResponseFieldMasksNumber map[string]string `json:"response_field_masks_number"`
+}
+
+// GoldenTest is a request captured by the SDK and the HAR it is expected to be captured as.
+type GoldenTest struct {
+	Name    string `json:"name"`
+	Fields  Fields `json:"fields"`
+	Args    Args   `json:"args"`
+	WantHAR string `json:"want_har"`
+}
+
+// LoadGoldenTests returns the golden tests read from the *_input.json and *_output.json files of dir, allowing the
+// middleware of the SDK and its framework integrations to be tested against the same requests and HARs.
+func LoadGoldenTests(t *testing.T, dir string) []GoldenTest {
+	t.Helper()
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	tests := []GoldenTest{}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), "_input.json") {
+			baseName := strings.TrimSuffix(file.Name(), "_input.json")
+
+			inputData, err := os.ReadFile(filepath.Join(dir, file.Name()))
+			require.NoError(t, err)
+
+			tt := GoldenTest{}
+			err = json.Unmarshal(inputData, &tt)
+			require.NoError(t, err)
+
+			outputData, err := os.ReadFile(filepath.Join(dir, baseName+"_output.json"))
+			require.NoError(t, err)
+
+			outputDataMinified := bytes.NewBuffer([]byte{})
+			err = json.Compact(outputDataMinified, outputData)
+			require.NoError(t, err)
+
+			tt.WantHAR = outputDataMinified.String()
+
+			tests = append(tests, tt)
+		}
+	}
+
+	return tests
+}
+
+// Setup sets the max capture size and the clock of the SDK to the values expected by the test, restoring them once the
+// test has finished. Tests calling Setup can't be run in parallel.
+func (tt GoldenTest) Setup(t *testing.T) {
+	t.Helper()
+
+	maxCaptureSize, timeNow, timeSince := hooks.MaxCaptureSize, hooks.TimeNow, hooks.TimeSince
+	t.Cleanup(func() {
+		hooks.MaxCaptureSize, hooks.TimeNow, hooks.TimeSince = maxCaptureSize, timeNow, timeSince
+	})
+
+	hooks.MaxCaptureSize = tt.Fields.MaxCaptureSize
+
+	startTime := tt.Args.RequestStartTime
+	if startTime.IsZero() {
+		startTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	hooks.TimeNow = func() time.Time {
+		return startTime
+	}
+
+	elapsedTime := 1 * time.Millisecond
+	if tt.Args.ElapsedTime != 0 {
+		elapsedTime = time.Duration(tt.Args.ElapsedTime) * time.Millisecond
+	}
+	hooks.TimeSince = func(t time.Time) time.Duration {
+		return elapsedTime
+	}
+}
+
+// WantResponseStatus returns the status of the response written by the handler of the test.
+func (tt GoldenTest) WantResponseStatus() int {
+	if tt.Args.ResponseStatus > 0 {
+		return tt.Args.ResponseStatus
+	}
+
+	return http.StatusOK
+}
+
+// NewRequest returns the request sent by the test.
+func (tt GoldenTest) NewRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	var req *http.Request
+	var err error
+	if tt.Args.Body == "" {
+		req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, nil)
+	} else {
+		req, err = http.NewRequest(tt.Args.Method, tt.Args.URL, bytes.NewBuffer([]byte(tt.Args.Body)))
+	}
+	require.NoError(t, err)
+
+	for _, header := range tt.Args.Headers {
+		for _, val := range header.Values {
+			req.Header.Add(header.Key, val)
+		}
+	}
+
+	return req
+}
+
+// MaskingOptions returns the masking options the handler of the test applies to the capture.
+func (tt GoldenTest) MaskingOptions() []speakeasy.MaskingOption {
+	opts := []speakeasy.MaskingOption{}
+
+	for k, v := range tt.Args.QueryStringMasks {
+		opts = append(opts, speakeasy.WithQueryStringMask([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.RequestHeaderMasks {
+		opts = append(opts, speakeasy.WithRequestHeaderMask([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.RequestCookieMasks {
+		opts = append(opts, speakeasy.WithRequestCookieMask([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.RequestFieldMasksString {
+		opts = append(opts, speakeasy.WithRequestFieldMaskString([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.RequestFieldMasksNumber {
+		opts = append(opts, speakeasy.WithRequestFieldMaskNumber([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.ResponseHeaderMasks {
+		opts = append(opts, speakeasy.WithResponseHeaderMask([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.ResponseCookieMasks {
+		opts = append(opts, speakeasy.WithResponseCookieMask([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.ResponseFieldMasksString {
+		opts = append(opts, speakeasy.WithResponseFieldMaskString([]string{k}, v))
+	}
+
+	for k, v := range tt.Args.ResponseFieldMasksNumber {
+		opts = append(opts, speakeasy.WithResponseFieldMaskNumber([]string{k}, v))
+	}
+
+	return opts
+}
@@ -0,0 +1,47 @@
+// Package speakeasytest provides utilities for testing the capture of requests by the SDK and its framework integrations,
+// such as an in-memory ingest server and the golden tests of the SDK.
+package speakeasytest
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Dialer returns a GRPCDialer for the SDK config that sends requests to an in-memory ingest server calling handlerFunc.
+func Dialer(handlerFunc func(ctx context.Context, req *ingest.IngestRequest)) func() func(context.Context, string) (net.Conn, error) {
+	return func() func(context.Context, string) (net.Conn, error) {
+		listener := bufconn.Listen(1024 * 1024)
+
+		server := grpc.NewServer()
+
+		ingest.RegisterIngestServiceServer(server, &ingestServer{
+			handlerFunc: handlerFunc,
+		})
+
+		go func() {
+			if err := server.Serve(listener); err != nil {
+				log.Fatal(err)
+			}
+		}()
+
+		return func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}
+	}
+}
+
+type ingestServer struct {
+	ingest.UnimplementedIngestServiceServer
+	handlerFunc func(ctx context.Context, req *ingest.IngestRequest)
+}
+
+func (s *ingestServer) Ingest(ctx context.Context, req *ingest.IngestRequest) (*ingest.IngestResponse, error) {
+	s.handlerFunc(ctx, req)
+
+	return &ingest.IngestResponse{}, nil
+}
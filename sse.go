@@ -0,0 +1,110 @@
+package speakeasy
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSSEMaxEvents   = 1000
+	defaultSSEMaxDuration = 5 * time.Minute
+)
+
+// ServerSentEventsConfig configures how text/event-stream responses are captured.
+type ServerSentEventsConfig struct {
+	// MaxEvents is the maximum number of events captured for a single response, once reached the capture is sent
+	// early and marked as incomplete (defaults to 1000).
+	MaxEvents int
+	// MaxDuration is the maximum duration a response is captured for, once exceeded the capture is sent
+	// early and marked as incomplete even if the stream is idle (defaults to 5 minutes).
+	MaxDuration time.Duration
+}
+
+type serverSentEvent struct {
+	ID    string  `json:"id,omitempty"`
+	Event string  `json:"event,omitempty"`
+	Data  string  `json:"data"`
+	Time  float64 `json:"time"`
+}
+
+// sseRecorder parses the event stream written by a handler into individual events,
+// following the parsing rules of https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type sseRecorder struct {
+	startTime time.Time
+	events    []*serverSentEvent
+	line      []byte
+	id        string
+	event     string
+	data      []string
+	hasData   bool
+}
+
+func newSSERecorder(startTime time.Time) *sseRecorder {
+	return &sseRecorder{
+		startTime: startTime,
+		events:    []*serverSentEvent{},
+	}
+}
+
+func (s *sseRecorder) write(p []byte) {
+	s.line = append(s.line, p...)
+
+	for {
+		i := bytes.IndexByte(s.line, '\n')
+		if i < 0 {
+			return
+		}
+
+		line := strings.TrimSuffix(string(s.line[:i]), "\r")
+		s.line = s.line[i+1:]
+
+		s.processLine(line)
+	}
+}
+
+func (s *sseRecorder) processLine(line string) {
+	if line == "" {
+		s.dispatch()
+		return
+	}
+
+	// lines starting with a colon are comments
+	if strings.HasPrefix(line, ":") {
+		return
+	}
+
+	field, value := line, ""
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+	}
+
+	switch field {
+	case "id":
+		s.id = value
+	case "event":
+		s.event = value
+	case "data":
+		s.data = append(s.data, value)
+		s.hasData = true
+	}
+}
+
+func (s *sseRecorder) dispatch() {
+	defer func() {
+		s.event = ""
+		s.data = nil
+		s.hasData = false
+	}()
+
+	if !s.hasData {
+		return
+	}
+
+	s.events = append(s.events, &serverSentEvent{
+		ID:    s.id,
+		Event: s.event,
+		Data:  strings.Join(s.data, "\n"),
+		Time:  float64(timeSince(s.startTime).Milliseconds()),
+	})
+}
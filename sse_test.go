@@ -0,0 +1,266 @@
+package speakeasy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEntry struct {
+	ServerSentEvents []struct {
+		ID    string  `json:"id"`
+		Event string  `json:"event"`
+		Data  string  `json:"data"`
+		Time  float64 `json:"time"`
+	} `json:"_serverSentEvents"`
+	Incomplete bool `json:"_incomplete"`
+}
+
+func TestSpeakeasy_Middleware_ServerSentEvents_Success(t *testing.T) {
+	type event struct {
+		ID    string
+		Event string
+		Data  string
+	}
+	tests := []struct {
+		name           string
+		config         speakeasy.ServerSentEventsConfig
+		stream         []string
+		wantEvents     []event
+		wantIncomplete bool
+	}{
+		{
+			name: "captures events from stream",
+			stream: []string{
+				": a comment\n\n",
+				"id: 1\nevent: greeting\ndata: hello\n\n",
+				"id: 2\r\ndata: multi\r\ndata: line\r\n\r\n",
+				"data: {\"secret\": ",
+				"\"value\"}\n\n",
+			},
+			wantEvents: []event{
+				{ID: "1", Event: "greeting", Data: "hello"},
+				{ID: "2", Data: "multi\nline"},
+				{ID: "2", Data: `{"secret": "__masked__"}`},
+			},
+		},
+		{
+			name: "captures stream early when event limit reached",
+			config: speakeasy.ServerSentEventsConfig{
+				MaxEvents: 2,
+			},
+			stream: []string{
+				"data: 1\n\n",
+				"data: 2\n\n",
+				"data: 3\n\n",
+			},
+			wantEvents: []event{
+				{Data: "1"},
+				{Data: "2"},
+			},
+			wantIncomplete: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			speakeasy.ExportSetMaxCaptureSize(9437184)
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			captured := make(chan sseEntry, 1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					var h struct {
+						Log struct {
+							Entries []sseEntry `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					captured <- h.Log.Entries[0]
+				}),
+				ServerSentEvents: tt.config,
+			})
+
+			w := httptest.NewRecorder()
+
+			req, err := http.NewRequest(http.MethodGet, "http://test.com/events", nil)
+			require.NoError(t, err)
+
+			var entry sseEntry
+
+			sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				ctrl.Masking(speakeasy.WithResponseFieldMaskString([]string{"secret"}))
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+
+				for i, chunk := range tt.stream {
+					_, err := w.Write([]byte(chunk))
+					assert.NoError(t, err)
+
+					// the capture should be sent while the handler is still streaming
+					if tt.wantIncomplete && i == len(tt.wantEvents)-1 {
+						entry = <-captured
+					}
+				}
+			})).ServeHTTP(w, req)
+
+			if !tt.wantIncomplete {
+				entry = <-captured
+			}
+
+			require.Len(t, entry.ServerSentEvents, len(tt.wantEvents))
+			for i, want := range tt.wantEvents {
+				assert.Equal(t, want.ID, entry.ServerSentEvents[i].ID)
+				assert.Equal(t, want.Event, entry.ServerSentEvents[i].Event)
+				assert.Equal(t, want.Data, entry.ServerSentEvents[i].Data)
+				assert.Equal(t, float64(1), entry.ServerSentEvents[i].Time)
+			}
+			assert.Equal(t, tt.wantIncomplete, entry.Incomplete)
+		})
+	}
+}
+
+func TestSpeakeasy_Middleware_ServerSentEvents_MaxDuration_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+	captured := make(chan sseEntry, 2)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []sseEntry `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			captured <- h.Log.Entries[0]
+		}),
+		ServerSentEvents: speakeasy.ServerSentEventsConfig{
+			MaxDuration: 50 * time.Millisecond,
+		},
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/events", nil)
+	require.NoError(t, err)
+
+	var entry sseEntry
+
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		_, err := w.Write([]byte("data: 1\n\n"))
+		assert.NoError(t, err)
+
+		// the capture should be sent once the duration is exceeded even though nothing more is written to the stream
+		select {
+		case entry = <-captured:
+		case <-time.After(5 * time.Second):
+			t.Error("stream wasn't captured once its max duration was exceeded")
+		}
+
+		_, err = w.Write([]byte("data: 2\n\n"))
+		assert.NoError(t, err)
+	})).ServeHTTP(w, req)
+
+	require.Len(t, entry.ServerSentEvents, 1)
+	assert.Equal(t, "1", entry.ServerSentEvents[0].Data)
+	assert.True(t, entry.Incomplete)
+	assert.Equal(t, "data: 1\n\ndata: 2\n\n", w.Body.String())
+
+	select {
+	case <-captured:
+		t.Error("stream was captured more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSpeakeasy_Middleware_ServerSentEvents_ControllerAfterCapture_Success(t *testing.T) {
+	speakeasy.ExportSetMaxCaptureSize(9437184)
+	speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+	type metadataEntry struct {
+		Metadata map[string]string `json:"_metadata"`
+	}
+
+	captured := make(chan metadataEntry, 1)
+
+	sdkInstance := speakeasy.New(speakeasy.Config{
+		APIKey:    testAPIKey,
+		ApiID:     testApiID,
+		VersionID: testVersionID,
+		GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+			var h struct {
+				Log struct {
+					Entries []metadataEntry `json:"entries"`
+				} `json:"log"`
+			}
+
+			err := json.Unmarshal([]byte(req.GetHar()), &h)
+			require.NoError(t, err)
+
+			captured <- h.Log.Entries[0]
+		}),
+		ServerSentEvents: speakeasy.ServerSentEventsConfig{
+			MaxEvents: 1,
+		},
+	})
+
+	w := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/events", nil)
+	require.NoError(t, err)
+
+	sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctrl, _ := speakeasy.MiddlewareController(req)
+		ctrl.Tag("before", "1")
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		_, err := w.Write([]byte("data: 1\n\n"))
+		assert.NoError(t, err)
+
+		// the controller is still used by the handler while the capture is being built
+		for i := 0; i < 100; i++ {
+			ctrl.Tag("after", strconv.Itoa(i))
+			ctrl.Metadata(map[string]string{"more": strconv.Itoa(i)})
+			ctrl.CustomerID(strconv.Itoa(i))
+			ctrl.RecordError(errors.New("after capture"))
+			ctrl.Masking(speakeasy.WithResponseHeaderMask([]string{strconv.Itoa(i)}))
+		}
+	})).ServeHTTP(w, req)
+
+	entry := <-captured
+	assert.Equal(t, map[string]string{"before": "1"}, entry.Metadata)
+}
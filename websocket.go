@@ -0,0 +1,386 @@
+package speakeasy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const defaultWebSocketMaxMessages = 100
+
+const (
+	wsOpcodeContinuation = 0x0
+	wsOpcodeText         = 0x1
+	wsOpcodeBinary       = 0x2
+	wsOpcodeClose        = 0x8
+)
+
+// WebSocketConfig configures the capture of requests upgraded to WebSocket connections.
+type WebSocketConfig struct {
+	// CaptureMessages enables the capture of text messages sent in both directions over the connection.
+	CaptureMessages bool
+	// MaxMessages is the maximum number of messages captured for a single connection, once reached the capture is sent
+	// without waiting for the connection to close (defaults to 100). Messages also count towards the max capture size, once exceeded
+	// the capture is sent in the same way.
+	MaxMessages int
+}
+
+type webSocketMessage struct {
+	// Type is "send" for messages sent by the client and "receive" for messages sent by the server, matching the HAR exports of browsers.
+	Type   string  `json:"type"`
+	Time   float64 `json:"time"`
+	Opcode int     `json:"opcode"`
+	Data   string  `json:"data"`
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// wsRecorder records the upgrade handshake and messages of a hijacked WebSocket connection.
+type wsRecorder struct {
+	mu              sync.Mutex
+	cw              *captureWriter
+	captureMessages bool
+	maxMessages     int
+	// budget is the number of bytes of messages that can still be captured without exceeding the max capture size.
+	budget        int
+	handshake     []byte
+	handshakeDone bool
+	messages      []*webSocketMessage
+	clientFrames  *wsFrameParser
+	serverFrames  *wsFrameParser
+	done          bool
+	onDone        func()
+}
+
+func newWSRecorder(cw *captureWriter, cfg WebSocketConfig, onDone func()) *wsRecorder {
+	ws := &wsRecorder{
+		cw:              cw,
+		captureMessages: cfg.CaptureMessages,
+		maxMessages:     cfg.MaxMessages,
+		budget:          cw.maxBuffer - cw.reqBuf.Len() - cw.resBuf.Len(),
+		// the handshake may have already been written through the ResponseWriter before the connection was hijacked
+		handshakeDone: cw.statusWritten,
+		messages:      []*webSocketMessage{},
+		onDone:        onDone,
+	}
+	ws.clientFrames = &wsFrameParser{budget: &ws.budget, onMessage: ws.recordMessage("send"), onClose: ws.markDone}
+	ws.serverFrames = &wsFrameParser{budget: &ws.budget, onMessage: ws.recordMessage("receive"), onClose: ws.markDone}
+
+	return ws
+}
+
+// wrap returns a net.Conn and bufio.ReadWriter that record the data sent over the hijacked connection.
+func (ws *wsRecorder) wrap(conn net.Conn, rw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+	wc := &wsConn{Conn: conn, ws: ws}
+
+	// Retain any data already buffered by the server so it is still read through the wrapped connection
+	if n := rw.Reader.Buffered(); n > 0 {
+		buffered, _ := rw.Reader.Peek(n)
+		wc.buffered = bytes.NewReader(append([]byte{}, buffered...))
+	}
+
+	return wc, bufio.NewReadWriter(bufio.NewReader(wc), bufio.NewWriter(wc))
+}
+
+func (ws *wsRecorder) GetMessages() []*webSocketMessage {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	return ws.messages
+}
+
+func (ws *wsRecorder) readFromClient(p []byte) {
+	ws.record(func() {
+		if ws.captureMessages {
+			ws.clientFrames.write(p)
+		}
+	})
+}
+
+func (ws *wsRecorder) writeToClient(p []byte) {
+	ws.record(func() {
+		ws.recordServerData(p)
+	})
+}
+
+func (ws *wsRecorder) close() {
+	ws.record(ws.markDone)
+}
+
+// record calls fn with the lock held, calling onDone once the lock is released if fn finished the recording.
+func (ws *wsRecorder) record(fn func()) {
+	ws.mu.Lock()
+
+	if ws.done {
+		ws.mu.Unlock()
+		return
+	}
+
+	fn()
+	done := ws.done
+
+	ws.mu.Unlock()
+
+	if done {
+		ws.onDone()
+	}
+}
+
+func (ws *wsRecorder) markDone() {
+	ws.done = true
+	ws.cw.finish()
+}
+
+func (ws *wsRecorder) recordServerData(p []byte) {
+	if !ws.handshakeDone {
+		ws.handshake = append(ws.handshake, p...)
+
+		i := bytes.Index(ws.handshake, []byte("\r\n\r\n"))
+		if i < 0 {
+			return
+		}
+
+		ws.handshakeDone = true
+		ws.recordHandshake(ws.handshake[:i+4])
+
+		p = ws.handshake[i+4:]
+		ws.handshake = nil
+	}
+
+	if ws.captureMessages {
+		ws.serverFrames.write(p)
+	}
+}
+
+func (ws *wsRecorder) recordHandshake(data []byte) {
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	ws.cw.status = res.StatusCode
+	ws.cw.statusWritten = true
+	ws.cw.resHeader = res.Header
+}
+
+func (ws *wsRecorder) recordMessage(messageType string) func(opcode byte, data []byte, truncated bool) {
+	return func(opcode byte, data []byte, truncated bool) {
+		// Messages count towards the max capture size, once exceeded the capture is sent without the rest of the messages
+		if truncated {
+			ws.cw.incomplete = true
+			ws.markDone()
+
+			return
+		}
+
+		ws.messages = append(ws.messages, &webSocketMessage{
+			Type:   messageType,
+			Time:   float64(timeNow().UnixNano()) / 1e9,
+			Opcode: int(opcode),
+			Data:   string(data),
+		})
+
+		if ws.maxMessages > 0 && len(ws.messages) >= ws.maxMessages {
+			ws.cw.incomplete = true
+			ws.markDone()
+		}
+	}
+}
+
+type wsConn struct {
+	net.Conn
+	ws       *wsRecorder
+	buffered *bytes.Reader
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	var n int
+	var err error
+
+	if c.buffered != nil && c.buffered.Len() > 0 {
+		n, err = c.buffered.Read(p)
+	} else {
+		n, err = c.Conn.Read(p)
+	}
+
+	c.ws.readFromClient(p[:n])
+
+	if err != nil {
+		c.ws.close()
+	}
+
+	return n, err
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+
+	c.ws.writeToClient(p[:n])
+
+	return n, err
+}
+
+func (c *wsConn) Close() error {
+	c.ws.close()
+
+	return c.Conn.Close()
+}
+
+// wsFrameParser incrementally parses WebSocket frames (https://www.rfc-editor.org/rfc/rfc6455#section-5.2) reassembling text messages.
+type wsFrameParser struct {
+	// budget is shared by the parsers of both directions, it is reduced by each byte of the messages buffered
+	budget    *int
+	onMessage func(opcode byte, data []byte, truncated bool)
+	onClose   func()
+
+	header    []byte
+	inFrame   bool
+	fin       bool
+	opcode    byte
+	masked    bool
+	maskKey   [4]byte
+	remaining uint64
+	read      uint64
+
+	messageOpcode byte
+	message       []byte
+	truncated     bool
+}
+
+func (p *wsFrameParser) write(b []byte) {
+	for len(b) > 0 {
+		if !p.inFrame {
+			b = p.readHeader(b)
+			continue
+		}
+
+		n := uint64(len(b))
+		if n > p.remaining {
+			n = p.remaining
+		}
+
+		p.readPayload(b[:n])
+		b = b[n:]
+
+		if p.remaining == 0 {
+			p.finishFrame()
+		}
+	}
+}
+
+func (p *wsFrameParser) readHeader(b []byte) []byte {
+	need := 2
+	if len(p.header) >= 2 {
+		need = wsHeaderLen(p.header)
+	}
+
+	for len(p.header) < need && len(b) > 0 {
+		p.header = append(p.header, b[0])
+		b = b[1:]
+
+		if len(p.header) == 2 {
+			need = wsHeaderLen(p.header)
+		}
+	}
+
+	if len(p.header) < need {
+		return b
+	}
+
+	p.fin = p.header[0]&0x80 != 0
+	p.opcode = p.header[0] & 0x0f
+	p.masked = p.header[1]&0x80 != 0
+
+	offset := 2
+	switch length := p.header[1] & 0x7f; length {
+	case 126:
+		p.remaining = uint64(binary.BigEndian.Uint16(p.header[2:4]))
+		offset += 2
+	case 127:
+		p.remaining = binary.BigEndian.Uint64(p.header[2:10])
+		offset += 8
+	default:
+		p.remaining = uint64(length)
+	}
+
+	if p.masked {
+		copy(p.maskKey[:], p.header[offset:offset+4])
+	}
+
+	p.header = p.header[:0]
+	p.inFrame = true
+	p.read = 0
+
+	if p.opcode == wsOpcodeText || p.opcode == wsOpcodeBinary {
+		p.messageOpcode = p.opcode
+		p.message = []byte{}
+		p.truncated = false
+	}
+
+	if p.remaining == 0 {
+		p.finishFrame()
+	}
+
+	return b
+}
+
+func (p *wsFrameParser) readPayload(b []byte) {
+	capturing := p.messageOpcode == wsOpcodeText && (p.opcode == wsOpcodeText || p.opcode == wsOpcodeContinuation)
+
+	for _, c := range b {
+		if capturing && *p.budget > 0 {
+			if p.masked {
+				c ^= p.maskKey[p.read%4]
+			}
+			p.message = append(p.message, c)
+			*p.budget--
+		} else if capturing {
+			p.truncated = true
+		}
+		p.read++
+	}
+
+	p.remaining -= uint64(len(b))
+}
+
+func (p *wsFrameParser) finishFrame() {
+	p.inFrame = false
+
+	switch {
+	case p.opcode == wsOpcodeClose:
+		p.onClose()
+	case p.opcode >= wsOpcodeClose:
+		// ignore other control frames such as ping and pong
+	case p.fin:
+		// only text messages are captured
+		if p.messageOpcode == wsOpcodeText {
+			p.onMessage(p.messageOpcode, p.message, p.truncated)
+		}
+		p.messageOpcode = 0
+		p.message = nil
+	}
+}
+
+func wsHeaderLen(header []byte) int {
+	n := 2
+
+	switch header[1] & 0x7f {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+
+	if header[1]&0x80 != 0 {
+		n += 4
+	}
+
+	return n
+}
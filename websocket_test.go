@@ -0,0 +1,255 @@
+package speakeasy_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/speakeasy-api/speakeasy-go-sdk"
+	"github.com/speakeasy-api/speakeasy-schemas/grpc/go/registry/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webSocketEntry struct {
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"response"`
+	WebSocketMessages []struct {
+		Type   string  `json:"type"`
+		Time   float64 `json:"time"`
+		Opcode int     `json:"opcode"`
+		Data   string  `json:"data"`
+	} `json:"_webSocketMessages"`
+	Incomplete bool `json:"_incomplete"`
+}
+
+func TestSpeakeasy_Middleware_WebSocket_Success(t *testing.T) {
+	type message struct {
+		Type string
+		Data string
+	}
+	tests := []struct {
+		name           string
+		config         speakeasy.WebSocketConfig
+		maxCaptureSize int
+		wantMessages   []message
+		wantIncomplete bool
+	}{
+		{
+			name: "captures handshake without messages",
+		},
+		{
+			name: "captures handshake and messages",
+			config: speakeasy.WebSocketConfig{
+				CaptureMessages: true,
+			},
+			wantMessages: []message{
+				{Type: "send", Data: `{"token": "__masked__"}`},
+				{Type: "receive", Data: `{"token": "__masked__"}`},
+				{Type: "send", Data: "goodbye"},
+				{Type: "receive", Data: "goodbye"},
+			},
+		},
+		{
+			name: "captures messages until limit reached",
+			config: speakeasy.WebSocketConfig{
+				CaptureMessages: true,
+				MaxMessages:     3,
+			},
+			wantMessages: []message{
+				{Type: "send", Data: `{"token": "__masked__"}`},
+				{Type: "receive", Data: `{"token": "__masked__"}`},
+				{Type: "send", Data: "goodbye"},
+			},
+			wantIncomplete: true,
+		},
+		{
+			name: "captures messages until max capture size reached",
+			config: speakeasy.WebSocketConfig{
+				CaptureMessages: true,
+			},
+			maxCaptureSize: 40,
+			wantMessages: []message{
+				{Type: "send", Data: `{"token": "__masked__"}`},
+				{Type: "receive", Data: `{"token": "__masked__"}`},
+			},
+			wantIncomplete: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maxCaptureSize == 0 {
+				tt.maxCaptureSize = 9437184
+			}
+
+			speakeasy.ExportSetMaxCaptureSize(tt.maxCaptureSize)
+			speakeasy.ExportSetTimeNow(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			speakeasy.ExportSetTimeSince(1 * time.Millisecond)
+
+			captured := make(chan webSocketEntry, 1)
+
+			sdkInstance := speakeasy.New(speakeasy.Config{
+				APIKey:    testAPIKey,
+				ApiID:     testApiID,
+				VersionID: testVersionID,
+				GRPCDialer: dialer(func(ctx context.Context, req *ingest.IngestRequest) {
+					assert.Equal(t, "/ws", req.PathHint)
+
+					var h struct {
+						Log struct {
+							Entries []webSocketEntry `json:"entries"`
+						} `json:"log"`
+					}
+
+					err := json.Unmarshal([]byte(req.GetHar()), &h)
+					require.NoError(t, err)
+
+					captured <- h.Log.Entries[0]
+				}),
+				WebSocket: tt.config,
+			})
+
+			r := http.NewServeMux()
+			r.Handle("/ws", sdkInstance.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctrl, _ := speakeasy.MiddlewareController(req)
+				ctrl.Masking(speakeasy.WithRequestFieldMaskString([]string{"token"}), speakeasy.WithResponseFieldMaskString([]string{"token"}))
+
+				conn, rw, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + webSocketAccept(req.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+				require.NoError(t, err)
+				require.NoError(t, rw.Flush())
+
+				// echo text messages until the client closes the connection
+				for {
+					opcode, data, err := readWebSocketFrame(rw.Reader)
+					if err != nil || opcode == 0x8 {
+						return
+					}
+
+					writeWebSocketFrame(t, rw.Writer, opcode, data, false)
+					require.NoError(t, rw.Flush())
+				}
+			})))
+
+			s := httptest.NewServer(r)
+			defer s.Close()
+
+			conn, err := net.Dial("tcp", s.Listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+			require.NoError(t, err)
+
+			br := bufio.NewReader(conn)
+
+			res, err := http.ReadResponse(br, nil)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+			for _, msg := range []string{`{"token": "secret"}`, "goodbye"} {
+				writeWebSocketFrame(t, conn, 0x1, []byte(msg), true)
+
+				opcode, data, err := readWebSocketFrame(br)
+				require.NoError(t, err)
+				assert.Equal(t, byte(0x1), opcode)
+				assert.Equal(t, msg, string(data))
+			}
+
+			writeWebSocketFrame(t, conn, 0x8, []byte{0x03, 0xe8}, true)
+
+			entry := <-captured
+
+			assert.Equal(t, http.StatusSwitchingProtocols, entry.Response.Status)
+			assert.Contains(t, entry.Response.Headers, struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			}{Name: "Upgrade", Value: "websocket"})
+
+			require.Len(t, entry.WebSocketMessages, len(tt.wantMessages))
+			for i, want := range tt.wantMessages {
+				assert.Equal(t, want.Type, entry.WebSocketMessages[i].Type)
+				assert.Equal(t, want.Data, entry.WebSocketMessages[i].Data)
+				assert.Equal(t, 1, entry.WebSocketMessages[i].Opcode)
+				assert.Equal(t, float64(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix()), entry.WebSocketMessages[i].Time)
+			}
+			assert.Equal(t, tt.wantIncomplete, entry.Incomplete)
+		})
+	}
+}
+
+func webSocketAccept(key string) string {
+	//nolint:gosec
+	h := sha1.New()
+	h.Write([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func writeWebSocketFrame(t *testing.T, w io.Writer, opcode byte, data []byte, masked bool) {
+	t.Helper()
+
+	require.Less(t, len(data), 126)
+
+	frame := []byte{0x80 | opcode, byte(len(data))}
+	if masked {
+		key := make([]byte, 4)
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+
+		frame[1] |= 0x80
+		frame = append(frame, key...)
+		for i, b := range data {
+			frame = append(frame, b^key[i%4])
+		}
+	} else {
+		frame = append(frame, data...)
+	}
+
+	_, err := w.Write(frame)
+	require.NoError(t, err)
+}
+
+func readWebSocketFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	var key []byte
+	if header[1]&0x80 != 0 {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	data := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range data {
+		if key != nil {
+			data[i] ^= key[i%4]
+		}
+	}
+
+	return header[0] & 0x0f, data, nil
+}